@@ -8,6 +8,7 @@ import (
 	"sort"
 	"time"
 
+	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 	"gorm.io/gorm/utils"
 )
@@ -126,18 +127,37 @@ func (p *processor) Execute(db *DB) *DB {
 		}
 	}
 
+	// soft deleted records can't be told apart without soft delete field
+	if _, ok := stmt.Clauses[onlyTrashedKey]; ok {
+		if stmt.Schema == nil {
+			db.AddError(fmt.Errorf("%w: OnlyTrashed requires a model with soft delete field", ErrModelValueRequired))
+		} else if field, _ := lookUpSoftDeleteField(stmt.Schema); field == nil {
+			db.AddError(fmt.Errorf("%w: %s has no soft delete field", ErrInvalidField, stmt.Schema.Name))
+		}
+	}
+
 	for _, f := range p.fns {
 		f(db)
 	}
 
 	if stmt.SQL.Len() > 0 {
-		db.Logger.Trace(stmt.Context, curTime, func() (string, int64) {
-			sql, vars := stmt.SQL.String(), stmt.Vars
-			if filter, ok := db.Logger.(ParamsFilter); ok {
-				sql, vars = filter.ParamsFilter(stmt.Context, stmt.SQL.String(), stmt.Vars...)
-			}
-			return db.Dialector.Explain(sql, vars...), db.RowsAffected
-		}, db.Error)
+		if tracer, ok := db.Logger.(logger.ParamsTracer); ok {
+			tracer.TraceParams(stmt.Context, curTime, func() (string, []interface{}, int64) {
+				sql, vars := stmt.SQL.String(), stmt.Vars
+				if filter, ok := db.Logger.(ParamsFilter); ok {
+					sql, vars = filter.ParamsFilter(stmt.Context, stmt.SQL.String(), stmt.Vars...)
+				}
+				return sql, vars, db.RowsAffected
+			}, db.Error)
+		} else {
+			db.Logger.Trace(stmt.Context, curTime, func() (string, int64) {
+				sql, vars := stmt.SQL.String(), stmt.Vars
+				if filter, ok := db.Logger.(ParamsFilter); ok {
+					sql, vars = filter.ParamsFilter(stmt.Context, stmt.SQL.String(), stmt.Vars...)
+				}
+				return db.Dialector.Explain(sql, vars...), db.RowsAffected
+			}, db.Error)
+		}
 	}
 
 	if !stmt.DB.DryRun {
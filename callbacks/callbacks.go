@@ -6,9 +6,9 @@ import (
 
 var (
 	createClauses = []string{"INSERT", "VALUES", "ON CONFLICT"}
-	queryClauses  = []string{"SELECT", "FROM", "WHERE", "GROUP BY", "ORDER BY", "LIMIT", "FOR"}
-	updateClauses = []string{"UPDATE", "SET", "WHERE"}
-	deleteClauses = []string{"DELETE", "FROM", "WHERE"}
+	queryClauses  = []string{"WITH", "SELECT", "FROM", "WHERE", "GROUP BY", "WINDOW", "ORDER BY", "LIMIT", "FOR"}
+	updateClauses = []string{"WITH", "UPDATE", "SET", "WHERE"}
+	deleteClauses = []string{"WITH", "DELETE", "FROM", "WHERE"}
 )
 
 type Config struct {
@@ -1,6 +1,7 @@
 package callbacks
 
 import (
+	"database/sql/driver"
 	"reflect"
 	"sort"
 
@@ -100,6 +101,28 @@ func Update(config *Config) func(db *gorm.DB) {
 					db.RowsAffected, _ = result.RowsAffected()
 				}
 			}
+
+			checkVersion(db)
+		}
+	}
+}
+
+// versionClause optimistic lock of the update, see gorm.Version
+func versionClause(stmt *gorm.Statement) (gorm.VersionUpdateClause, bool) {
+	if c, ok := stmt.Clauses["version_enabled"]; ok {
+		v, ok := c.Expression.(gorm.VersionUpdateClause)
+		return v, ok
+	}
+	return gorm.VersionUpdateClause{}, false
+}
+
+// checkVersion reports ErrOptimisticLock if the version condition matched nothing, or sets the new version
+func checkVersion(db *gorm.DB) {
+	if v, ok := versionClause(db.Statement); ok && v.Checked && db.Error == nil {
+		if db.RowsAffected == 0 {
+			db.AddError(gorm.ErrOptimisticLock)
+		} else if db.Statement.ReflectValue.CanAddr() {
+			db.AddError(v.Field.Set(db.Statement.Context, db.Statement.ReflectValue, gorm.Version{Int64: v.Current + 1, Valid: true}))
 		}
 	}
 }
@@ -192,6 +215,15 @@ func ConvertToAssignments(stmt *gorm.Statement) (set clause.Set) {
 	case map[string]interface{}:
 		set = make([]clause.Assignment, 0, len(value))
 
+		// values of schema fields are set to a new record, so they are serialized like fields of records
+		var (
+			record  reflect.Value
+			updated []*schema.Field
+		)
+		if stmt.Schema != nil {
+			record = reflect.New(stmt.Schema.ModelType).Elem()
+		}
+
 		keys := make([]string, 0, len(value))
 		for k := range value {
 			keys = append(keys, k)
@@ -208,6 +240,18 @@ func ConvertToAssignments(stmt *gorm.Statement) (set clause.Set) {
 				if field := stmt.Schema.LookUpField(k); field != nil {
 					if field.DBName != "" {
 						if v, ok := selectColumns[field.DBName]; (ok && v) || (!ok && !restricted) {
+							if !isExpression(value[k]) {
+								err := field.Set(stmt.Context, record, value[k])
+								if field.Serializer != nil {
+									if stmt.AddError(err) != nil {
+										continue
+									}
+									kv, _ = field.ValueOf(stmt.Context, record)
+								}
+								if err == nil {
+									updated = append(updated, field)
+								}
+							}
 							set = append(set, clause.Assignment{Column: clause.Column{Name: field.DBName}, Value: kv})
 							assignValue(field, value[k])
 						}
@@ -223,6 +267,10 @@ func ConvertToAssignments(stmt *gorm.Statement) (set clause.Set) {
 			}
 		}
 
+		if stmt.Schema != nil {
+			set = assignBlindIndexes(stmt, stmt.Schema, record, set, updated, assignValue)
+		}
+
 		if !stmt.SkipHooks && stmt.Schema != nil {
 			for _, dbName := range stmt.Schema.DBNames {
 				field := stmt.Schema.LookUpField(dbName)
@@ -258,6 +306,7 @@ func ConvertToAssignments(stmt *gorm.Statement) (set clause.Set) {
 
 		switch updatingValue.Kind() {
 		case reflect.Struct:
+			var updated []*schema.Field
 			set = make([]clause.Assignment, 0, len(stmt.Schema.FieldsByDBName))
 			for _, dbName := range stmt.Schema.DBNames {
 				if field := updatingSchema.LookUpField(dbName); field != nil {
@@ -279,6 +328,7 @@ func ConvertToAssignments(stmt *gorm.Statement) (set clause.Set) {
 
 							if (ok || !isZero) && field.Updatable {
 								set = append(set, clause.Assignment{Column: clause.Column{Name: field.DBName}, Value: value})
+								updated = append(updated, field)
 								assignField := field
 								if isDiffSchema {
 									if originField := stmt.Schema.LookUpField(dbName); originField != nil {
@@ -295,10 +345,69 @@ func ConvertToAssignments(stmt *gorm.Statement) (set clause.Set) {
 					}
 				}
 			}
+
+			set = assignBlindIndexes(stmt, updatingSchema, updatingValue, set, updated, func(field *schema.Field, value interface{}) {
+				if originField := stmt.Schema.LookUpField(field.DBName); originField != nil {
+					assignValue(originField, value)
+				}
+			})
 		default:
 			stmt.AddError(gorm.ErrInvalidData)
 		}
 	}
 
+	// version is always increased, regardless of the updating value and selected columns
+	if v, ok := versionClause(stmt); ok && len(set) > 0 {
+		assignments := make(clause.Set, 0, len(set)+1)
+		for _, assignment := range set {
+			if assignment.Column.Name != v.Field.DBName {
+				assignments = append(assignments, assignment)
+			}
+		}
+
+		set = append(assignments, clause.Assignment{
+			Column: clause.Column{Name: v.Field.DBName},
+			Value:  clause.Expr{SQL: "? + 1", Vars: []interface{}{clause.Column{Name: v.Field.DBName}}},
+		})
+	}
+
 	return
 }
+
+func isExpression(value interface{}) bool {
+	switch value.(type) {
+	case clause.Expression, *gorm.DB:
+		return true
+	}
+	return false
+}
+
+// assignBlindIndexes assigns blind indexes of updated fields, which are computed from fields of record, so they
+// keep matching updated values
+func assignBlindIndexes(stmt *gorm.Statement, s *schema.Schema, record reflect.Value, set clause.Set, updated []*schema.Field, assignValue func(field *schema.Field, value interface{})) clause.Set {
+	assigned := make(map[string]bool, len(set))
+	for _, assignment := range set {
+		assigned[assignment.Column.Name] = true
+	}
+
+	for _, field := range updated {
+		for _, indexField := range s.BlindIndexFieldsOf(field) {
+			if assigned[indexField.DBName] || !indexField.Updatable {
+				continue
+			}
+
+			index, _ := indexField.ValueOf(stmt.Context, record)
+			if valuer, ok := index.(driver.Valuer); ok {
+				var err error
+				if index, err = valuer.Value(); stmt.AddError(err) != nil {
+					continue
+				}
+			}
+
+			set = append(set, clause.Assignment{Column: clause.Column{Name: indexField.DBName}, Value: index})
+			assignValue(indexField, index)
+			assigned[indexField.DBName] = true
+		}
+	}
+	return set
+}
@@ -85,6 +85,37 @@ func (db *DB) Table(name string, args ...interface{}) (tx *DB) {
 	return
 }
 
+// With specify a common table expression, subquery could be a *gorm.DB, a clause.Expression or a raw SQL string
+//
+//	// query users through a CTE
+//	db.With("adults", db.Model(&User{}).Where("age >= ?", 18)).Table("adults").Find(&users)
+//	// update with a CTE
+//	db.With("banned", db.Model(&Ban{}).Select("user_id")).Model(&User{}).Where("id IN (SELECT user_id FROM banned)").Update("active", false)
+func (db *DB) With(name string, subquery interface{}) (tx *DB) {
+	return with(db, false, name, subquery)
+}
+
+// WithRecursive specify a recursive common table expression
+//
+//	db.WithRecursive("tree", gorm.Expr("SELECT id, parent_id FROM orgs WHERE id = ? UNION ALL SELECT orgs.id, orgs.parent_id FROM orgs JOIN tree ON orgs.parent_id = tree.id", 1)).
+//		Table("tree").Find(&orgs)
+func (db *DB) WithRecursive(name string, subquery interface{}) (tx *DB) {
+	return with(db, true, name, subquery)
+}
+
+func with(db *DB, recursive bool, name string, subquery interface{}) (tx *DB) {
+	tx = db.getInstance()
+	if sql, ok := subquery.(string); ok {
+		subquery = clause.Expr{SQL: sql}
+	}
+
+	tx.Statement.AddClause(clause.With{
+		Recursive: recursive,
+		CTEs:      []clause.CTE{{Name: name, Subquery: subquery}},
+	})
+	return
+}
+
 // Distinct specify distinct fields that you want querying
 //
 //	// Select distinct names of users
@@ -457,6 +488,16 @@ func (db *DB) Unscoped() (tx *DB) {
 	return
 }
 
+// OnlyTrashed queries soft deleted records only
+//
+//	var users []User
+//	db.OnlyTrashed().Find(&users)
+func (db *DB) OnlyTrashed() (tx *DB) {
+	tx = db.getInstance()
+	tx.Statement.Clauses[onlyTrashedKey] = clause.Clause{}
+	return
+}
+
 func (db *DB) Raw(sql string, values ...interface{}) (tx *DB) {
 	tx = db.getInstance()
 	tx.Statement.SQL = strings.Builder{}
@@ -3,10 +3,25 @@ package gorm
 import (
 	"fmt"
 	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 	"reflect"
 	"strings"
+	"sync"
 )
 
+// defaultMaxPageSize default Config.MaxPageSize
+const defaultMaxPageSize = 50
+
+// PageResult page result of FindPage
+type PageResult struct {
+	Total     int64       `json:"total"`
+	Pages     int         `json:"pages"`
+	PageIndex int         `json:"pageIndex"`
+	PageSize  int         `json:"pageSize"`
+	HasNext   bool        `json:"hasNext"`
+	Items     interface{} `json:"items"`
+}
+
 // ScanCount scan count value to a int64
 func (db *DB) ScanCount() int64 {
 	tx := db.getInstance()
@@ -32,19 +47,86 @@ func (db *DB) Page(pageIndex, pageSize int) (tx *DB) {
 	return
 }
 
-// PageLimit Limit specify the number of records to be retrieved pageSize 50
+// PageLimit Limit specify the number of records to be retrieved, pageSize is capped by Config.MaxPageSize
 func (db *DB) PageLimit(pageIndex, pageSize int) (tx *DB) {
 	if pageIndex < 1 {
 		pageIndex = 1
 	}
-	if pageSize > 50 {
-		pageSize = 50
+	if maxPageSize := db.maxPageSize(); pageSize > maxPageSize {
+		pageSize = maxPageSize
 	}
 	tx = db.getInstance()
 	tx.Limit(pageSize).Offset((pageIndex - 1) * pageSize)
 	return
 }
 
+// FindPage find records of the page into dest and count total records, pageSize is capped by Config.MaxPageSize,
+// ORDER BY, LIMIT and preloads are not applied to the count query
+//
+//	var users []User
+//	result, err := db.Where("age > ?", 18).Order("id").FindPage(&users, 2, 20)
+//	// result.Total, result.Pages, result.HasNext, result.Items -> &users
+func (db *DB) FindPage(dest interface{}, pageIndex, pageSize int) (*PageResult, error) {
+	if pageIndex < 1 {
+		pageIndex = 1
+	}
+	if maxPageSize := db.maxPageSize(); pageSize < 1 || pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	var (
+		total   int64
+		countTx = db.Session(&Session{Initialized: true})
+		findTx  = db.Session(&Session{Initialized: true})
+	)
+
+	delete(countTx.Statement.Clauses, "LIMIT")
+	countTx.Statement.Preloads = map[string][]interface{}{}
+	if countTx.Statement.Model == nil {
+		countTx.Statement.Model = dest
+	}
+
+	findTx = findTx.Limit(pageSize).Offset((pageIndex - 1) * pageSize)
+
+	_, inTransaction := db.Statement.ConnPool.(TxCommitter)
+	if db.ConcurrentFindPage && !inTransaction {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			countTx = countTx.Count(&total)
+		}()
+		findTx = findTx.Find(dest)
+		wg.Wait()
+	} else if countTx = countTx.Count(&total); countTx.Error == nil {
+		findTx = findTx.Find(dest)
+	}
+
+	if countTx.Error != nil {
+		return nil, countTx.Error
+	}
+	if findTx.Error != nil {
+		return nil, findTx.Error
+	}
+
+	pages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	return &PageResult{
+		Total:     total,
+		Pages:     pages,
+		PageIndex: pageIndex,
+		PageSize:  pageSize,
+		HasNext:   pageIndex < pages,
+		Items:     dest,
+	}, nil
+}
+
+func (db *DB) maxPageSize() int {
+	if db.MaxPageSize > 0 {
+		return db.MaxPageSize
+	}
+	return defaultMaxPageSize
+}
+
 // OrderByAsc Order specify order when retrieve records from database
 //
 //	db.Order("name DESC")
@@ -65,16 +147,7 @@ func (db *DB) OrderByAsc(orderName string) (tx *DB) {
 //	db.Order("name DESC")
 //	db.Order(clause.OrderByColumn{Column: clause.Column{Name: "name"}, Desc: true})
 func (db *DB) OrderByAscGBK(orderName string) (tx *DB) {
-	if orderName == "" {
-		return
-	}
-	tx = db.getInstance()
-	tx.Statement.AddClause(clause.OrderBy{
-		Columns: []clause.OrderByColumn{{
-			Column: clause.Column{Name: fmt.Sprintf(" CONVERT(%s USING gbk) %s", orderName, "ASC"), Raw: true},
-		}},
-	})
-	return
+	return db.OrderByCollate(orderName, clause.CollationGBK, false)
 }
 
 // OrderByDesc Order specify order when retrieve records from database
@@ -97,14 +170,22 @@ func (db *DB) OrderByDesc(orderName string) (tx *DB) {
 //	db.Order("name DESC")
 //	db.Order(clause.OrderByColumn{Column: clause.Column{Name: "name"}, Desc: true})
 func (db *DB) OrderByDescGBK(orderName string) (tx *DB) {
-	if orderName == "" {
+	return db.OrderByCollate(orderName, clause.CollationGBK, true)
+}
+
+// OrderByCollate Order specify collation aware order when retrieve records from database,
+// the collated expression is rendered by the Dialector, see CollateDialector, statements fail with
+// ErrUnsupportedDriver if the Dialector doesn't support the collation
+//
+//	db.OrderByCollate("name", clause.CollationGBK, false)
+//	db.OrderByCollate("name", "utf8mb4_unicode_ci", true)
+func (db *DB) OrderByCollate(column, collation string, desc bool) (tx *DB) {
+	tx = db.getInstance()
+	if column == "" {
 		return
 	}
-	tx = db.getInstance()
 	tx.Statement.AddClause(clause.OrderBy{
-		Columns: []clause.OrderByColumn{{
-			Column: clause.Column{Name: fmt.Sprintf(" CONVERT(%s USING gbk) %s", orderName, "DESC"), Raw: true},
-		}},
+		Columns: []clause.OrderByColumn{{Column: clause.Column{Name: column}, Desc: desc, Collation: collation}},
 	})
 	return
 }
@@ -127,22 +208,11 @@ func (db *DB) OrderByStructColumn(v interface{}, orderColumns []*OrderColumn) (t
 		if len(columnName) <= 0 {
 			continue
 		}
+		orderColumn := clause.OrderByColumn{Column: clause.Column{Name: columnName}, Desc: item.Desc}
 		if item.GBK {
-			orderTypes := "ASC"
-			if item.Desc {
-				orderTypes = "DESC"
-			}
-			tx.Statement.AddClause(clause.OrderBy{
-				Columns: []clause.OrderByColumn{{
-					Column: clause.Column{Name: fmt.Sprintf(" CONVERT(%s USING gbk) %s", columnName, orderTypes), Raw: true},
-				}},
-			})
-		} else {
-			tx.Statement.AddClause(clause.OrderBy{
-				Columns: []clause.OrderByColumn{{Column: clause.Column{Name: columnName}, Desc: item.Desc}},
-			})
+			orderColumn.Collation = clause.CollationGBK
 		}
-
+		tx.Statement.AddClause(clause.OrderBy{Columns: []clause.OrderByColumn{orderColumn}})
 	}
 	return
 }
@@ -184,18 +254,117 @@ func (db *DB) OrderByStructGBK(v interface{}, orderName string, desc ...bool) (t
 	if orderName == "" {
 		return
 	}
-	orderTypes := "ASC"
-	if len(desc) > 0 {
-		orderTypes = "DESC"
+	return tx.OrderByCollate(orderName, clause.CollationGBK, len(desc) > 0 && desc[0])
+}
+
+// ApplyQuery apply sorts and filters from request input, field names are validated against the model's schema
+// and could be either the column name or the json name of a field
+//
+//	db.ApplyQuery(&User{}, gorm.QuerySpec{
+//		Filters: []*gorm.QueryFilter{{Field: "age", Op: gorm.FilterGt, Value: 18}, {Field: "role", Op: gorm.FilterIn, Value: []interface{}{"admin", "owner"}}},
+//		Sorts:   []*gorm.OrderColumn{{Name: "createdAt", Desc: true}},
+//	}).Find(&users)
+func (db *DB) ApplyQuery(model interface{}, spec QuerySpec) (tx *DB) {
+	tx = db.getInstance()
+	s, err := schema.Parse(model, tx.cacheStore, tx.NamingStrategy)
+	if err != nil {
+		tx.AddError(err)
+		return
+	}
+
+	lookUpField := func(name string) *schema.Field {
+		if field, ok := s.FieldsByDBName[name]; ok && field.Readable {
+			return field
+		}
+		for _, field := range s.Fields {
+			if field.DBName != "" && field.Readable {
+				if jsonName := strings.Split(field.Tag.Get("json"), ",")[0]; jsonName != "" && jsonName == name {
+					return field
+				}
+			}
+		}
+		return nil
+	}
+
+	exprs := make([]clause.Expression, 0, len(spec.Filters))
+	for _, filter := range spec.Filters {
+		field := lookUpField(filter.Field)
+		if field == nil {
+			tx.AddError(fmt.Errorf("%w: unknown filter field %s", ErrInvalidField, filter.Field))
+			return
+		}
+
+		column := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+		switch filter.Op {
+		case FilterEq, "":
+			exprs = append(exprs, clause.Eq{Column: column, Value: filter.Value})
+		case FilterNe:
+			exprs = append(exprs, clause.Neq{Column: column, Value: filter.Value})
+		case FilterGt:
+			exprs = append(exprs, clause.Gt{Column: column, Value: filter.Value})
+		case FilterGte:
+			exprs = append(exprs, clause.Gte{Column: column, Value: filter.Value})
+		case FilterLt:
+			exprs = append(exprs, clause.Lt{Column: column, Value: filter.Value})
+		case FilterLte:
+			exprs = append(exprs, clause.Lte{Column: column, Value: filter.Value})
+		case FilterLike:
+			exprs = append(exprs, clause.Like{Column: column, Value: filter.Value})
+		case FilterIn, FilterBetween:
+			values, ok := filterValues(filter.Value)
+			if !ok || (filter.Op == FilterBetween && len(values) != 2) {
+				tx.AddError(fmt.Errorf("%w: invalid %s value for field %s", ErrInvalidData, filter.Op, filter.Field))
+				return
+			}
+
+			if filter.Op == FilterIn {
+				exprs = append(exprs, clause.IN{Column: column, Values: values})
+			} else {
+				exprs = append(exprs, clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []interface{}{column, values[0], values[1]}})
+			}
+		default:
+			tx.AddError(fmt.Errorf("%w: unsupported filter operator %s", ErrInvalidData, filter.Op))
+			return
+		}
+	}
+
+	if len(exprs) > 0 {
+		tx.Statement.AddClause(clause.Where{Exprs: exprs})
+	}
+
+	for _, sort := range spec.Sorts {
+		field := lookUpField(sort.Name)
+		if field == nil {
+			tx.AddError(fmt.Errorf("%w: unknown sort field %s", ErrInvalidField, sort.Name))
+			return
+		}
+
+		orderColumn := clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Desc: sort.Desc}
+		if sort.GBK {
+			orderColumn.Collation = clause.CollationGBK
+		}
+		tx.Statement.AddClause(clause.OrderBy{Columns: []clause.OrderByColumn{orderColumn}})
 	}
-	tx.Statement.AddClause(clause.OrderBy{
-		Columns: []clause.OrderByColumn{{
-			Column: clause.Column{Name: fmt.Sprintf(" CONVERT(%s USING gbk) %s", orderName, orderTypes), Raw: true},
-		}},
-	})
 	return
 }
 
+func filterValues(value interface{}) ([]interface{}, bool) {
+	if values, ok := value.([]interface{}); ok {
+		return values, len(values) > 0
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() != reflect.Slice && reflectValue.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	values := make([]interface{}, reflectValue.Len())
+	for i := range values {
+		values[i] = reflectValue.Index(i).Interface()
+	}
+	return values, len(values) > 0
+}
+
 // SelectByStruct Select specify fields that you want when querying, creating, updating
 func (db *DB) SelectByStruct(v interface{}, args ...interface{}) (tx *DB) {
 	tx = db.getInstance()
@@ -246,9 +415,8 @@ func getStructFieldTagArray(v interface{}) []string {
 		if tag == "-" {
 			continue
 		}
-		if tag != "" {
-			tag = replaceKeyWord(tag)
-			jsonArray = append(jsonArray, tag)
+		if column := schema.ParseTagSetting(tag, ";")["COLUMN"]; column != "" {
+			jsonArray = append(jsonArray, replaceKeyWord(column))
 			continue
 		}
 		//json,omitempty
@@ -278,6 +446,31 @@ func replaceKeyWord(tag string) string {
 	return tag
 }
 
+// Filter operators of QueryFilter
+const (
+	FilterEq      = "eq"
+	FilterNe      = "ne"
+	FilterGt      = "gt"
+	FilterGte     = "gte"
+	FilterLt      = "lt"
+	FilterLte     = "lte"
+	FilterLike    = "like"
+	FilterIn      = "in"
+	FilterBetween = "between"
+)
+
+// QuerySpec sorts and filters from request input, see ApplyQuery
+type QuerySpec struct {
+	Filters []*QueryFilter `json:"filters"`
+	Sorts   []*OrderColumn `json:"sorts"`
+}
+
+type QueryFilter struct {
+	Field string      `json:"field"` // 字段名称
+	Op    string      `json:"op"`    // 操作符 eq ne gt gte lt lte like in between, default eq
+	Value interface{} `json:"value"` // in between 为数组
+}
+
 type OrderColumn struct {
 	Name string `json:"name"` // 字段名称
 	Desc bool   `json:"desc"` // 排序类型 true Desc false ASC default ASC
@@ -0,0 +1,166 @@
+package gorm_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+type sqlRecorder struct {
+	logger.Interface
+	mu   sync.Mutex
+	SQLs []string
+}
+
+func (r *sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	r.mu.Lock()
+	r.SQLs = append(r.SQLs, sql)
+	r.mu.Unlock()
+}
+
+func TestFindPage(t *testing.T) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: recorder, MaxPageSize: 30})
+
+	var users []tests.User
+	result, err := db.Where("age > ?", 18).Preload("Pets").Order("name").FindPage(&users, 3, 100)
+	if err != nil {
+		t.Fatalf("failed to find page, got %v", err)
+	}
+
+	tests.AssertEqual(t, recorder.SQLs, []string{
+		"SELECT count(*) FROM `users` WHERE age > 18 AND `users`.`deleted_at` IS NULL",
+		"SELECT * FROM `users` WHERE age > 18 AND `users`.`deleted_at` IS NULL ORDER BY name LIMIT 30 OFFSET 60",
+	})
+
+	if result.PageIndex != 3 || result.PageSize != 30 || result.Items != &users {
+		t.Errorf("unexpected page result %+v", result)
+	}
+
+	recorder.SQLs = nil
+	db.PageLimit(2, 100).Find(&users)
+	tests.AssertEqual(t, recorder.SQLs, []string{"SELECT * FROM `users` WHERE `users`.`deleted_at` IS NULL LIMIT 30 OFFSET 30"})
+}
+
+func TestConcurrentFindPage(t *testing.T) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	sqlDB := sql.OpenDB(connector{rowsDriver{columns: []string{"id"}, rows: [][]driver.Value{{int64(3)}}}})
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{ConnPool: sqlDB, Logger: recorder, ConcurrentFindPage: true})
+
+	// pages of the same chain are found by goroutines at the same time, run with -race
+	var (
+		tx = db.Model(&tests.User{}).Where("age > ?", 18).Order("name")
+		wg sync.WaitGroup
+	)
+	for i := 1; i <= 4; i++ {
+		wg.Add(1)
+		go func(pageIndex int) {
+			defer wg.Done()
+
+			var users []tests.User
+			result, err := tx.FindPage(&users, pageIndex, 1)
+			if err != nil {
+				t.Errorf("failed to find page %v, got %v", pageIndex, err)
+				return
+			}
+
+			if result.Total != 3 || result.Pages != 3 || result.HasNext != (pageIndex < 3) || len(users) != 1 || users[0].ID != 3 {
+				t.Errorf("unexpected page result of page %v, got %+v, users %+v", pageIndex, result, users)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	sort.Strings(recorder.SQLs)
+	tests.AssertEqual(t, recorder.SQLs, []string{
+		"SELECT * FROM `users` WHERE age > 18 AND `users`.`deleted_at` IS NULL ORDER BY name LIMIT 1",
+		"SELECT * FROM `users` WHERE age > 18 AND `users`.`deleted_at` IS NULL ORDER BY name LIMIT 1 OFFSET 1",
+		"SELECT * FROM `users` WHERE age > 18 AND `users`.`deleted_at` IS NULL ORDER BY name LIMIT 1 OFFSET 2",
+		"SELECT * FROM `users` WHERE age > 18 AND `users`.`deleted_at` IS NULL ORDER BY name LIMIT 1 OFFSET 3",
+		"SELECT count(*) FROM `users` WHERE age > 18 AND `users`.`deleted_at` IS NULL",
+		"SELECT count(*) FROM `users` WHERE age > 18 AND `users`.`deleted_at` IS NULL",
+		"SELECT count(*) FROM `users` WHERE age > 18 AND `users`.`deleted_at` IS NULL",
+		"SELECT count(*) FROM `users` WHERE age > 18 AND `users`.`deleted_at` IS NULL",
+	})
+}
+
+type mysqlDialector struct {
+	tests.DummyDialector
+}
+
+func (mysqlDialector) Name() string {
+	return "mysql"
+}
+
+func TestOrderByCollate(t *testing.T) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: recorder})
+	mysqlDB, _ := gorm.Open(mysqlDialector{}, &gorm.Config{DryRun: true, Logger: recorder})
+
+	var users []tests.User
+	db.OrderByCollate("name", "utf8mb4_bin", true).Find(&users)
+	mysqlDB.OrderByAscGBK("name").OrderByDescGBK("users.age").Find(&users)
+	tests.AssertEqual(t, recorder.SQLs, []string{
+		"SELECT * FROM `users` WHERE `users`.`deleted_at` IS NULL ORDER BY `name` COLLATE utf8mb4_bin DESC",
+		"SELECT * FROM `users` WHERE `users`.`deleted_at` IS NULL ORDER BY CONVERT(`name` USING gbk),CONVERT(`users`.`age` USING gbk) DESC",
+	})
+
+	if err := db.OrderByCollate("name", "bin; DROP TABLE users", false).Find(&users).Error; !errors.Is(err, gorm.ErrInvalidData) {
+		t.Errorf("invalid collation should be rejected, got %v", err)
+	}
+
+	if err := db.OrderByAscGBK("name").Find(&users).Error; !errors.Is(err, gorm.ErrUnsupportedDriver) {
+		t.Errorf("collation unsupported by the dialector should be rejected, got %v", err)
+	}
+}
+
+type querySpecUser struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Age       int       `json:"age"`
+	CreatedAt time.Time `json:"createdAt"`
+	Secret    string    `json:"-" gorm:"-"`
+}
+
+func TestApplyQuery(t *testing.T) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: recorder})
+
+	var users []querySpecUser
+	db.ApplyQuery(&querySpecUser{}, gorm.QuerySpec{
+		Filters: []*gorm.QueryFilter{
+			{Field: "name", Op: gorm.FilterLike, Value: "jin%"},
+			{Field: "age", Op: gorm.FilterBetween, Value: []int{18, 30}},
+			{Field: "id", Op: gorm.FilterIn, Value: []interface{}{1, 2}},
+		},
+		Sorts: []*gorm.OrderColumn{{Name: "createdAt", Desc: true}, {Name: "name"}},
+	}).Find(&users)
+	tests.AssertEqual(t, recorder.SQLs, []string{
+		"SELECT * FROM `query_spec_users` WHERE `query_spec_users`.`name` LIKE \"jin%\" AND (`query_spec_users`.`age` BETWEEN 18 AND 30) AND `query_spec_users`.`id` IN (1,2) ORDER BY `query_spec_users`.`created_at` DESC,`query_spec_users`.`name`",
+	})
+
+	for _, spec := range []gorm.QuerySpec{
+		{Filters: []*gorm.QueryFilter{{Field: "secret", Value: "x"}}},
+		{Filters: []*gorm.QueryFilter{{Field: "name = 1 OR 1", Value: "x"}}},
+		{Filters: []*gorm.QueryFilter{{Field: "age", Op: "regexp", Value: "x"}}},
+		{Filters: []*gorm.QueryFilter{{Field: "age", Op: gorm.FilterBetween, Value: []int{1}}}},
+		{Sorts: []*gorm.OrderColumn{{Name: "age; DROP TABLE users"}}},
+	} {
+		if err := db.ApplyQuery(&querySpecUser{}, spec).Find(&users).Error; !errors.Is(err, gorm.ErrInvalidField) && !errors.Is(err, gorm.ErrInvalidData) {
+			t.Errorf("invalid query spec %+v should be rejected, got %v", spec, err)
+		}
+	}
+}
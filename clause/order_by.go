@@ -1,9 +1,18 @@
 package clause
 
+// CollationGBK pinyin ordering for chinese characters, rendered as CONVERT(column USING gbk) on MySQL
+const CollationGBK = "gbk"
+
 type OrderByColumn struct {
-	Column  Column
-	Desc    bool
-	Reorder bool
+	Column    Column
+	Desc      bool
+	Reorder   bool
+	Collation string
+}
+
+// Collator builder that renders collation aware expressions
+type Collator interface {
+	Collate(column Column, collation string) Expression
 }
 
 type OrderBy struct {
@@ -26,7 +35,16 @@ func (orderBy OrderBy) Build(builder Builder) {
 				builder.WriteByte(',')
 			}
 
-			builder.WriteQuoted(column.Column)
+			if column.Collation == "" {
+				builder.WriteQuoted(column.Column)
+			} else if collator, ok := builder.(Collator); ok {
+				collator.Collate(column.Column, column.Collation).Build(builder)
+			} else {
+				builder.WriteQuoted(column.Column)
+				builder.WriteString(" COLLATE ")
+				builder.WriteString(column.Collation)
+			}
+
 			if column.Desc {
 				builder.WriteString(" DESC")
 			}
@@ -48,6 +48,17 @@ func TestOrderBy(t *testing.T) {
 			"SELECT * FROM `users` ORDER BY FIELD(id, ?,?,?)",
 			[]interface{}{1, 2, 3},
 		},
+		{
+			[]clause.Interface{
+				clause.Select{}, clause.From{}, clause.OrderBy{
+					Columns: []clause.OrderByColumn{
+						{Column: clause.Column{Name: "name"}, Collation: "utf8mb4_unicode_ci", Desc: true},
+						{Column: clause.Column{Table: "users", Name: "nickname"}, Collation: `"zh-x-icu"`},
+					},
+				},
+			},
+			"SELECT * FROM `users` ORDER BY `name` COLLATE utf8mb4_unicode_ci DESC,`users`.`nickname` COLLATE \"zh-x-icu\"", nil,
+		},
 	}
 
 	for idx, result := range results {
@@ -0,0 +1,227 @@
+package clause
+
+import "strconv"
+
+// window frame units
+const (
+	FrameRows   = "ROWS"
+	FrameRange  = "RANGE"
+	FrameGroups = "GROUPS"
+)
+
+// window frame bounds, see also Preceding, Following
+const (
+	UnboundedPreceding = "UNBOUNDED PRECEDING"
+	CurrentRow         = "CURRENT ROW"
+	UnboundedFollowing = "UNBOUNDED FOLLOWING"
+)
+
+// Preceding frame bound of n rows (or values for RANGE) before current row
+func Preceding(n int) string {
+	return strconv.Itoa(n) + " PRECEDING"
+}
+
+// Following frame bound of n rows (or values for RANGE) after current row
+func Following(n int) string {
+	return strconv.Itoa(n) + " FOLLOWING"
+}
+
+// Frame window frame, End could be blank to use the start bound only
+type Frame struct {
+	Unit  string
+	Start string
+	End   string
+}
+
+// Build build window frame
+func (frame Frame) Build(builder Builder) {
+	unit := frame.Unit
+	if unit == "" {
+		unit = FrameRows
+	}
+	builder.WriteString(unit)
+
+	if frame.End == "" {
+		builder.WriteByte(' ')
+		builder.WriteString(frame.Start)
+	} else {
+		builder.WriteString(" BETWEEN ")
+		builder.WriteString(frame.Start)
+		builder.WriteString(" AND ")
+		builder.WriteString(frame.End)
+	}
+}
+
+// Window window specification, Name refers to a named window that this one extends
+type Window struct {
+	Name        string
+	PartitionBy []Column
+	OrderBy     []OrderByColumn
+	Frame       *Frame
+}
+
+// Build build window specification without parentheses
+func (window Window) Build(builder Builder) {
+	var written bool
+	writeSep := func() {
+		if written {
+			builder.WriteByte(' ')
+		}
+		written = true
+	}
+
+	if window.Name != "" {
+		writeSep()
+		builder.WriteQuoted(window.Name)
+	}
+
+	if len(window.PartitionBy) > 0 {
+		writeSep()
+		builder.WriteString("PARTITION BY ")
+		for idx, column := range window.PartitionBy {
+			if idx > 0 {
+				builder.WriteByte(',')
+			}
+			builder.WriteQuoted(column)
+		}
+	}
+
+	if len(window.OrderBy) > 0 {
+		writeSep()
+		builder.WriteString("ORDER BY ")
+		OrderBy{Columns: window.OrderBy}.Build(builder)
+	}
+
+	if window.Frame != nil {
+		writeSep()
+		window.Frame.Build(builder)
+	}
+}
+
+// Over window function call, renders `function OVER (window)`, or `function OVER name` if only Window.Name is set
+//
+//	db.Select("*, ? AS rank", clause.Over{
+//		Function: clause.Rank(),
+//		Window:   clause.Window{PartitionBy: []clause.Column{{Name: "dept"}}, OrderBy: []clause.OrderByColumn{{Column: clause.Column{Name: "salary"}, Desc: true}}},
+//	}).Find(&employees)
+type Over struct {
+	Function Expression
+	Window   Window
+}
+
+// Build build window function call
+func (over Over) Build(builder Builder) {
+	over.Function.Build(builder)
+	builder.WriteString(" OVER ")
+
+	if w := over.Window; w.Name != "" && len(w.PartitionBy) == 0 && len(w.OrderBy) == 0 && w.Frame == nil {
+		builder.WriteQuoted(w.Name)
+		return
+	}
+
+	builder.WriteByte('(')
+	over.Window.Build(builder)
+	builder.WriteByte(')')
+}
+
+// WindowFunc window or aggregate function call, args are added as vars, use Column for columns
+type WindowFunc struct {
+	Name string
+	Args []interface{}
+}
+
+// Build build function call
+func (fc WindowFunc) Build(builder Builder) {
+	builder.WriteString(fc.Name)
+	builder.WriteByte('(')
+	for idx, arg := range fc.Args {
+		if idx > 0 {
+			builder.WriteByte(',')
+		}
+		builder.AddVar(builder, arg)
+	}
+	builder.WriteByte(')')
+}
+
+func RowNumber() WindowFunc {
+	return WindowFunc{Name: "ROW_NUMBER"}
+}
+
+func Rank() WindowFunc {
+	return WindowFunc{Name: "RANK"}
+}
+
+func DenseRank() WindowFunc {
+	return WindowFunc{Name: "DENSE_RANK"}
+}
+
+// Lag value of column offset rows before current row
+func Lag(column Column, offset int) WindowFunc {
+	return WindowFunc{Name: "LAG", Args: []interface{}{column, offset}}
+}
+
+// Lead value of column offset rows after current row
+func Lead(column Column, offset int) WindowFunc {
+	return WindowFunc{Name: "LEAD", Args: []interface{}{column, offset}}
+}
+
+// Sum sum of column, used with Over for running sums
+func Sum(column Column) WindowFunc {
+	return WindowFunc{Name: "SUM", Args: []interface{}{column}}
+}
+
+// NamedWindow named window definition of WINDOW clause
+type NamedWindow struct {
+	Name   string
+	Window Window
+}
+
+// Windows named windows clause, referenced by Over with Window.Name
+type Windows struct {
+	Windows []NamedWindow
+}
+
+// Name window clause name
+func (windows Windows) Name() string {
+	return "WINDOW"
+}
+
+// Build build window clause
+func (windows Windows) Build(builder Builder) {
+	for idx, window := range windows.Windows {
+		if idx > 0 {
+			builder.WriteByte(',')
+		}
+
+		builder.WriteQuoted(window.Name)
+		builder.WriteString(" AS (")
+		window.Window.Build(builder)
+		builder.WriteByte(')')
+	}
+}
+
+// MergeClause merge window clauses, windows with the same name are replaced
+func (windows Windows) MergeClause(clause *Clause) {
+	if v, ok := clause.Expression.(Windows); ok {
+		merged := make([]NamedWindow, len(v.Windows), len(v.Windows)+len(windows.Windows))
+		copy(merged, v.Windows)
+
+		for _, window := range windows.Windows {
+			replaced := false
+			for idx, w := range merged {
+				if w.Name == window.Name {
+					merged[idx] = window
+					replaced = true
+					break
+				}
+			}
+
+			if !replaced {
+				merged = append(merged, window)
+			}
+		}
+		windows.Windows = merged
+	}
+
+	clause.Expression = windows
+}
@@ -0,0 +1,59 @@
+package clause_test
+
+import (
+	"fmt"
+	"testing"
+
+	"gorm.io/gorm/clause"
+)
+
+func TestWindow(t *testing.T) {
+	byAge := clause.Window{
+		PartitionBy: []clause.Column{{Name: "role"}},
+		OrderBy:     []clause.OrderByColumn{{Column: clause.Column{Name: "age"}, Desc: true}},
+	}
+
+	results := []struct {
+		Clauses []clause.Interface
+		Result  string
+		Vars    []interface{}
+	}{
+		{
+			[]clause.Interface{clause.Select{Expression: clause.Expr{SQL: "name, ? AS rn", Vars: []interface{}{clause.Over{Function: clause.RowNumber(), Window: byAge}}}}, clause.From{}},
+			"SELECT name, ROW_NUMBER() OVER (PARTITION BY `role` ORDER BY `age` DESC) AS rn FROM `users`",
+			nil,
+		},
+		{
+			[]clause.Interface{clause.Select{Expression: clause.Expr{SQL: "?", Vars: []interface{}{clause.Over{
+				Function: clause.Sum(clause.Column{Table: clause.CurrentTable, Name: "age"}),
+				Window:   clause.Window{OrderBy: []clause.OrderByColumn{{Column: clause.Column{Name: "id"}}}, Frame: &clause.Frame{Start: clause.UnboundedPreceding, End: clause.CurrentRow}},
+			}}}}, clause.From{}},
+			"SELECT SUM(`users`.`age`) OVER (ORDER BY `id` ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) FROM `users`",
+			nil,
+		},
+		{
+			[]clause.Interface{clause.Select{Expression: clause.Expr{SQL: "?", Vars: []interface{}{clause.Over{
+				Function: clause.Lag(clause.Column{Name: "age"}, 1),
+				Window:   clause.Window{Name: "w", Frame: &clause.Frame{Unit: clause.FrameRange, Start: clause.Preceding(3), End: clause.Following(3)}},
+			}}}}, clause.From{}},
+			"SELECT LAG(`age`,?) OVER (`w` RANGE BETWEEN 3 PRECEDING AND 3 FOLLOWING) FROM `users`",
+			[]interface{}{1},
+		},
+		{
+			[]clause.Interface{
+				clause.Select{Expression: clause.Expr{SQL: "?", Vars: []interface{}{clause.Over{Function: clause.Rank(), Window: clause.Window{Name: "w"}}}}}, clause.From{},
+				clause.Windows{Windows: []clause.NamedWindow{{Name: "w", Window: clause.Window{PartitionBy: []clause.Column{{Name: "age"}}}}}},
+				clause.Windows{Windows: []clause.NamedWindow{{Name: "w", Window: byAge}, {Name: "w2", Window: clause.Window{Name: "w", Frame: &clause.Frame{Start: clause.CurrentRow}}}}},
+				clause.OrderBy{Expression: clause.Expr{SQL: "?", Vars: []interface{}{clause.Over{Function: clause.DenseRank(), Window: clause.Window{Name: "w2"}}}}},
+			},
+			"SELECT RANK() OVER `w` FROM `users` WINDOW `w` AS (PARTITION BY `role` ORDER BY `age` DESC),`w2` AS (`w` ROWS CURRENT ROW) ORDER BY DENSE_RANK() OVER `w2`",
+			nil,
+		},
+	}
+
+	for idx, result := range results {
+		t.Run(fmt.Sprintf("case #%v", idx), func(t *testing.T) {
+			checkBuildClauses(t, result.Clauses, result.Result, result.Vars)
+		})
+	}
+}
@@ -1,3 +1,91 @@
 package clause
 
-type With struct{}
+const (
+	CTEMaterialized    = "MATERIALIZED"
+	CTENotMaterialized = "NOT MATERIALIZED"
+)
+
+// With common table expressions clause
+type With struct {
+	Recursive bool
+	CTEs      []CTE
+}
+
+// CTE common table expression, Subquery could be a *gorm.DB or an Expression
+type CTE struct {
+	Name         string
+	Columns      []string
+	Materialized string
+	Subquery     interface{}
+}
+
+// Name with clause name
+func (with With) Name() string {
+	return "WITH"
+}
+
+// Build build with clause
+func (with With) Build(builder Builder) {
+	builder.WriteString("WITH ")
+	if with.Recursive {
+		builder.WriteString("RECURSIVE ")
+	}
+
+	for idx, cte := range with.CTEs {
+		if idx > 0 {
+			builder.WriteByte(',')
+		}
+		cte.Build(builder)
+	}
+}
+
+// Build build common table expression
+func (cte CTE) Build(builder Builder) {
+	builder.WriteQuoted(cte.Name)
+	if len(cte.Columns) > 0 {
+		builder.WriteByte(' ')
+		builder.WriteQuoted(cte.Columns)
+	}
+
+	builder.WriteString(" AS ")
+	if cte.Materialized != "" {
+		builder.WriteString(cte.Materialized)
+		builder.WriteByte(' ')
+	}
+
+	builder.WriteByte('(')
+	if cte.Subquery != nil {
+		builder.AddVar(builder, cte.Subquery)
+	}
+	builder.WriteByte(')')
+}
+
+// MergeClause merge with clauses, a CTE with the same name replaces the existing one
+func (with With) MergeClause(clause *Clause) {
+	clause.Name = ""
+
+	if v, ok := clause.Expression.(With); ok {
+		ctes := make([]CTE, len(v.CTEs), len(v.CTEs)+len(with.CTEs))
+		copy(ctes, v.CTEs)
+
+		for _, cte := range with.CTEs {
+			replaced := false
+			for idx, c := range ctes {
+				if c.Name == cte.Name {
+					ctes[idx] = cte
+					replaced = true
+					break
+				}
+			}
+
+			if !replaced {
+				ctes = append(ctes, cte)
+			}
+		}
+
+		with.CTEs = ctes
+		with.Recursive = with.Recursive || v.Recursive
+	}
+
+	clause.Expression = with
+}
@@ -0,0 +1,74 @@
+package clause_test
+
+import (
+	"fmt"
+	"testing"
+
+	"gorm.io/gorm/clause"
+)
+
+func TestWith(t *testing.T) {
+	results := []struct {
+		Clauses []clause.Interface
+		Result  string
+		Vars    []interface{}
+	}{
+		{
+			[]clause.Interface{clause.With{CTEs: []clause.CTE{{
+				Name:     "adults",
+				Subquery: clause.Expr{SQL: "SELECT * FROM users WHERE age >= ?", Vars: []interface{}{18}},
+			}}}, clause.Select{}, clause.From{Tables: []clause.Table{{Name: "adults"}}}},
+			"WITH `adults` AS (SELECT * FROM users WHERE age >= ?) SELECT * FROM `adults`",
+			[]interface{}{18},
+		},
+		{
+			[]clause.Interface{clause.With{Recursive: true, CTEs: []clause.CTE{{
+				Name:     "tree",
+				Columns:  []string{"id", "parent_id"},
+				Subquery: clause.Expr{SQL: "SELECT id, parent_id FROM orgs WHERE id = ? UNION ALL SELECT orgs.id, orgs.parent_id FROM orgs JOIN tree ON orgs.parent_id = tree.id", Vars: []interface{}{1}},
+			}}}, clause.Select{}, clause.From{Tables: []clause.Table{{Name: "tree"}}}},
+			"WITH RECURSIVE `tree` (`id`,`parent_id`) AS (SELECT id, parent_id FROM orgs WHERE id = ? UNION ALL SELECT orgs.id, orgs.parent_id FROM orgs JOIN tree ON orgs.parent_id = tree.id) SELECT * FROM `tree`",
+			[]interface{}{1},
+		},
+		{
+			[]clause.Interface{
+				clause.With{CTEs: []clause.CTE{{Name: "a", Materialized: clause.CTEMaterialized, Subquery: clause.Expr{SQL: "SELECT 1"}}}},
+				clause.With{Recursive: true, CTEs: []clause.CTE{{Name: "b", Materialized: clause.CTENotMaterialized, Subquery: clause.Expr{SQL: "SELECT 2"}}}},
+				clause.Select{}, clause.From{},
+			},
+			"WITH RECURSIVE `a` AS MATERIALIZED (SELECT 1),`b` AS NOT MATERIALIZED (SELECT 2) SELECT * FROM `users`",
+			nil,
+		},
+		{
+			[]clause.Interface{
+				clause.With{CTEs: []clause.CTE{{Name: "a", Subquery: clause.Expr{SQL: "SELECT ?", Vars: []interface{}{1}}}}},
+				clause.With{CTEs: []clause.CTE{{Name: "a", Subquery: clause.Expr{SQL: "SELECT ?", Vars: []interface{}{2}}}}},
+				clause.Select{}, clause.From{},
+			},
+			"WITH `a` AS (SELECT ?) SELECT * FROM `users`",
+			[]interface{}{2},
+		},
+		{
+			[]clause.Interface{
+				clause.With{CTEs: []clause.CTE{{Name: "adults", Subquery: db.Table("users").Select("id").Where("age >= ?", 18)}}},
+				clause.Select{}, clause.From{Tables: []clause.Table{{Name: "adults"}}},
+			},
+			"WITH `adults` AS (SELECT id FROM `users` WHERE age >= ?) SELECT * FROM `adults`",
+			[]interface{}{18},
+		},
+		{
+			[]clause.Interface{
+				clause.With{CTEs: []clause.CTE{{Name: "expired", Subquery: clause.Expr{SQL: "SELECT id FROM users WHERE age > ?", Vars: []interface{}{100}}}}},
+				clause.Delete{}, clause.From{}, clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "id IN (SELECT id FROM expired)"}}},
+			},
+			"WITH `expired` AS (SELECT id FROM users WHERE age > ?) DELETE FROM `users` WHERE id IN (SELECT id FROM expired)",
+			[]interface{}{100},
+		},
+	}
+
+	for idx, result := range results {
+		t.Run(fmt.Sprintf("case #%v", idx), func(t *testing.T) {
+			checkBuildClauses(t, result.Clauses, result.Result, result.Vars)
+		})
+	}
+}
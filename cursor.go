@@ -0,0 +1,332 @@
+package gorm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Cursor keyset pagination options, After and Before are tokens returned by a previous CursorPage
+type Cursor struct {
+	After  string
+	Before string
+	Limit  int
+}
+
+// CursorPage keyset pagination result, Next and Previous are blank if there are no more pages in that direction
+type CursorPage struct {
+	Next     string
+	Previous string
+}
+
+type cursorPayload struct {
+	Backward bool              `json:"b,omitempty"`
+	Columns  string            `json:"c"`
+	Values   []json.RawMessage `json:"v"`
+}
+
+type keysetColumn struct {
+	Column clause.Column
+	Field  *schema.Field
+	Desc   bool
+}
+
+// Paginate finds a page of records with keyset pagination, the keyset is built from current ORDER BY columns,
+// the primary key is appended to them as a tie-breaker if missing. Cursor tokens are signed with Config.CursorSigningKey,
+// which is required
+//
+//	var users []User
+//	page, err := db.Order("created_at DESC").Paginate(&users, gorm.Cursor{Limit: 20})
+//	// next page
+//	page, err = db.Order("created_at DESC").Paginate(&users, gorm.Cursor{After: page.Next, Limit: 20})
+//	// previous page
+//	page, err = db.Order("created_at DESC").Paginate(&users, gorm.Cursor{Before: page.Previous, Limit: 20})
+func (db *DB) Paginate(dest interface{}, cursor Cursor) (*CursorPage, error) {
+	if len(db.CursorSigningKey) == 0 {
+		return nil, ErrMissingCursorSigningKey
+	}
+
+	if cursor.Limit <= 0 {
+		return nil, fmt.Errorf("%w: limit should be greater than zero", ErrInvalidCursor)
+	}
+
+	if cursor.After != "" && cursor.Before != "" {
+		return nil, fmt.Errorf("%w: after and before can't be used together", ErrInvalidCursor)
+	}
+
+	tx := db.getInstance()
+	model := tx.Statement.Model
+	if model == nil {
+		model = dest
+	}
+
+	if err := tx.Statement.Parse(model); err != nil {
+		return nil, err
+	}
+
+	columns, err := tx.Statement.keysetColumns()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		token    = cursor.After
+		backward = cursor.Before != ""
+		names    = keysetColumnNames(columns)
+	)
+
+	if backward {
+		token = cursor.Before
+	}
+
+	if token != "" {
+		values, err := tx.decodeCursor(token, names, backward, columns)
+		if err != nil {
+			return nil, err
+		}
+		tx.Statement.addKeysetCondition(columns, values, backward)
+	}
+
+	orderColumns := make([]clause.OrderByColumn, len(columns))
+	for idx, column := range columns {
+		orderColumns[idx] = clause.OrderByColumn{Column: column.Column, Desc: column.Desc != backward}
+	}
+	orderColumns[0].Reorder = true
+
+	tx = tx.Order(clause.OrderBy{Columns: orderColumns}).Limit(cursor.Limit + 1).Find(dest)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	results := reflect.Indirect(reflect.ValueOf(dest))
+	if results.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("%w: dest should be a pointer to slice", ErrInvalidCursor)
+	}
+
+	hasMore := results.Len() > cursor.Limit
+	if hasMore {
+		results.Set(results.Slice(0, cursor.Limit))
+	}
+
+	if backward {
+		swap := reflect.Swapper(results.Interface())
+		for i, j := 0, results.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	page := &CursorPage{}
+	if results.Len() == 0 {
+		return page, nil
+	}
+
+	if hasMore || backward {
+		if page.Next, err = tx.encodeCursor(names, false, columns, results.Index(results.Len()-1)); err != nil {
+			return nil, err
+		}
+	}
+
+	if (backward && hasMore) || (!backward && token != "") {
+		if page.Previous, err = tx.encodeCursor(names, true, columns, results.Index(0)); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// addKeysetCondition add a keyset condition that seeks rows after (or before if backward) values ordered by columns
+func (stmt *Statement) addKeysetCondition(columns []keysetColumn, values []interface{}, backward bool) {
+	exprs := make([]clause.Expression, len(columns))
+	for idx, column := range columns {
+		conds := make([]clause.Expression, 0, idx+1)
+		for i := 0; i < idx; i++ {
+			conds = append(conds, clause.Eq{Column: columns[i].Column, Value: values[i]})
+		}
+
+		if column.Desc != backward {
+			conds = append(conds, clause.Lt{Column: column.Column, Value: values[idx]})
+		} else {
+			conds = append(conds, clause.Gt{Column: column.Column, Value: values[idx]})
+		}
+		exprs[idx] = clause.And(conds...)
+	}
+
+	// keyset condition must be applied to all existing OR conditions
+	if len(exprs) == 1 {
+		stmt.AddAndConditions(exprs...)
+	} else {
+		stmt.AddAndConditions(clause.Or(exprs...))
+	}
+}
+
+// keysetColumns parse ORDER BY columns into keyset columns
+func (stmt *Statement) keysetColumns() ([]keysetColumn, error) {
+	var columns []keysetColumn
+
+	addColumn := func(table, name string, desc bool) error {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil || field.DBName == "" {
+			return fmt.Errorf("%w: order column %s not found in %s", ErrInvalidCursor, name, stmt.Schema.Name)
+		}
+
+		if table == "" || table == stmt.Table {
+			table = clause.CurrentTable
+		}
+
+		for _, column := range columns {
+			if column.Field == field && column.Column.Table == table {
+				return nil
+			}
+		}
+
+		columns = append(columns, keysetColumn{Column: clause.Column{Table: table, Name: field.DBName}, Field: field, Desc: desc})
+		return nil
+	}
+
+	if c, ok := stmt.Clauses["ORDER BY"]; ok && c.Expression != nil {
+		orderBy, ok := c.Expression.(clause.OrderBy)
+		if !ok || orderBy.Expression != nil {
+			return nil, fmt.Errorf("%w: order by expression is not supported", ErrInvalidCursor)
+		}
+
+		for _, column := range orderBy.Columns {
+			if column.Collation != "" {
+				// keyset comparisons don't follow the collation of ORDER BY
+				return nil, fmt.Errorf("%w: collated order %s is not supported", ErrInvalidCursor, column.Column.Name)
+			}
+
+			if !column.Column.Raw {
+				table, name := column.Column.Table, column.Column.Name
+				if table == clause.CurrentTable {
+					table = ""
+				}
+
+				if name == clause.PrimaryKey && stmt.Schema.PrioritizedPrimaryField != nil {
+					name = stmt.Schema.PrioritizedPrimaryField.DBName
+				} else if t, n := matchName(name); n != "" && n != "*" {
+					// column names from OrderByStruct might be quoted or prefixed with table
+					name = n
+					if t != "" {
+						table = t
+					}
+				}
+
+				if err := addColumn(table, name, column.Desc); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			for _, part := range strings.Split(column.Column.Name, ",") {
+				fields := strings.Fields(part)
+				desc := column.Desc
+				switch {
+				case len(fields) == 2 && strings.EqualFold(fields[1], "DESC"):
+					desc = true
+				case len(fields) == 2 && strings.EqualFold(fields[1], "ASC"):
+				case len(fields) != 1:
+					return nil, fmt.Errorf("%w: unsupported order %s", ErrInvalidCursor, strings.TrimSpace(part))
+				}
+
+				table, name := matchName(fields[0])
+				if name == "" || name == "*" {
+					return nil, fmt.Errorf("%w: unsupported order %s", ErrInvalidCursor, strings.TrimSpace(part))
+				}
+
+				if err := addColumn(table, name, desc); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if pf := stmt.Schema.PrioritizedPrimaryField; pf != nil {
+		desc := false
+		if len(columns) > 0 {
+			desc = columns[len(columns)-1].Desc
+		}
+
+		if err := addColumn("", pf.DBName, desc); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%w: order columns or primary key required", ErrInvalidCursor)
+	}
+
+	return columns, nil
+}
+
+func keysetColumnNames(columns []keysetColumn) string {
+	names := make([]string, len(columns))
+	for idx, column := range columns {
+		names[idx] = column.Field.DBName
+		if column.Desc {
+			names[idx] += " DESC"
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func (db *DB) encodeCursor(names string, backward bool, columns []keysetColumn, row reflect.Value) (string, error) {
+	payload := cursorPayload{Backward: backward, Columns: names, Values: make([]json.RawMessage, len(columns))}
+	for idx, column := range columns {
+		value, _ := column.Field.ValueOf(db.Statement.Context, row)
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		payload.Values[idx] = data
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, db.CursorSigningKey)
+	mac.Write(data)
+	return base64.RawURLEncoding.EncodeToString(append(mac.Sum(nil), data...)), nil
+}
+
+func (db *DB) decodeCursor(token, names string, backward bool, columns []keysetColumn) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(data) <= sha256.Size {
+		return nil, ErrInvalidCursor
+	}
+
+	mac := hmac.New(sha256.New, db.CursorSigningKey)
+	mac.Write(data[sha256.Size:])
+	if !hmac.Equal(mac.Sum(nil), data[:sha256.Size]) {
+		return nil, fmt.Errorf("%w: signature mismatch", ErrInvalidCursor)
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(data[sha256.Size:], &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	if payload.Columns != names || payload.Backward != backward || len(payload.Values) != len(columns) {
+		return nil, fmt.Errorf("%w: order columns mismatch", ErrInvalidCursor)
+	}
+
+	values := make([]interface{}, len(columns))
+	for idx, column := range columns {
+		value := reflect.New(column.Field.FieldType)
+		if err := json.Unmarshal(payload.Values[idx], value.Interface()); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		values[idx] = value.Elem().Interface()
+	}
+
+	return values, nil
+}
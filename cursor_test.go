@@ -0,0 +1,68 @@
+package gorm_test
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+func TestPaginate(t *testing.T) {
+	var (
+		recorder = logger.Recorder.New()
+		db, _    = gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: recorder, CursorSigningKey: []byte("secret")})
+	)
+
+	// dry run mode won't touch dest, so prepared rows are treated as query results
+	users := []tests.User{{Name: "c", Age: 30}, {Name: "b", Age: 20}, {Name: "a", Age: 20}}
+	users[0].ID, users[1].ID, users[2].ID = 3, 2, 1
+
+	page, err := db.Order("age desc").Order("name").Paginate(&users, gorm.Cursor{Limit: 2})
+	if err != nil {
+		t.Fatalf("failed to paginate, got %v", err)
+	}
+	tests.AssertEqual(t, recorder.SQL, "SELECT * FROM `users` WHERE `users`.`deleted_at` IS NULL ORDER BY `users`.`age` DESC,`users`.`name`,`users`.`id` LIMIT 3")
+
+	if page.Next == "" || page.Previous != "" || len(users) != 2 {
+		t.Fatalf("first page should only have next cursor, got %+v, %v users", page, len(users))
+	}
+
+	users = []tests.User{{Name: "a", Age: 20}}
+	users[0].ID = 1
+	page, err = db.Order("age desc").Order("name").Paginate(&users, gorm.Cursor{After: page.Next, Limit: 2})
+	if err != nil {
+		t.Fatalf("failed to paginate with cursor, got %v", err)
+	}
+	tests.AssertEqual(t, recorder.SQL, "SELECT * FROM `users` WHERE (`users`.`age` < 20 OR (`users`.`age` = 20 AND `users`.`name` > \"b\") OR (`users`.`age` = 20 AND `users`.`name` = \"b\" AND `users`.`id` > 2)) AND `users`.`deleted_at` IS NULL ORDER BY `users`.`age` DESC,`users`.`name`,`users`.`id` LIMIT 3")
+
+	if page.Next != "" || page.Previous == "" {
+		t.Fatalf("last page should only have previous cursor, got %+v", page)
+	}
+
+	users = []tests.User{{Name: "b", Age: 20}}
+	users[0].ID = 2
+	if _, err = db.Order("age desc").Order("name").Paginate(&users, gorm.Cursor{Before: page.Previous, Limit: 2}); err != nil {
+		t.Fatalf("failed to paginate backward, got %v", err)
+	}
+	tests.AssertEqual(t, recorder.SQL, "SELECT * FROM `users` WHERE (`users`.`age` > 20 OR (`users`.`age` = 20 AND `users`.`name` < \"a\") OR (`users`.`age` = 20 AND `users`.`name` = \"a\" AND `users`.`id` < 1)) AND `users`.`deleted_at` IS NULL ORDER BY `users`.`age`,`users`.`name` DESC,`users`.`id` DESC LIMIT 3")
+
+	if _, err = db.Order("name").Paginate(&users, gorm.Cursor{After: page.Previous, Limit: 2}); !errors.Is(err, gorm.ErrInvalidCursor) {
+		t.Errorf("cursor of different order should be rejected, got %v", err)
+	}
+
+	otherDB, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, CursorSigningKey: []byte("other")})
+	if _, err = otherDB.Order("age desc").Order("name").Paginate(&users, gorm.Cursor{Before: page.Previous, Limit: 2}); !errors.Is(err, gorm.ErrInvalidCursor) {
+		t.Errorf("cursor signed with another key should be rejected, got %v", err)
+	}
+
+	unsignedDB, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+	if _, err = unsignedDB.Order("name").Paginate(&users, gorm.Cursor{Limit: 2}); !errors.Is(err, gorm.ErrMissingCursorSigningKey) {
+		t.Errorf("paginate without signing key should fail, got %v", err)
+	}
+
+	if _, err = db.OrderByAscGBK("name").Paginate(&users, gorm.Cursor{Limit: 2}); !errors.Is(err, gorm.ErrInvalidCursor) {
+		t.Errorf("unsupported order should be rejected, got %v", err)
+	}
+}
@@ -51,4 +51,12 @@ var (
 	ErrForeignKeyViolated = errors.New("violates foreign key constraint")
 	// ErrCheckConstraintViolated occurs when there is a check constraint violation
 	ErrCheckConstraintViolated = errors.New("violates check constraint")
+	// ErrInvalidCursor invalid or tampered pagination cursor
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrMissingCursorSigningKey Paginate without Config.CursorSigningKey
+	ErrMissingCursorSigningKey = errors.New("missing cursor signing key")
+	// ErrOptimisticLock record has been updated or deleted since it was read, see Version
+	ErrOptimisticLock = errors.New("optimistic lock failed")
+	// ErrStopIteration returned by the callback of Iterate to stop iterating without error
+	ErrStopIteration = errors.New("stop iteration")
 )
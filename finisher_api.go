@@ -1,11 +1,14 @@
 package gorm
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
@@ -69,6 +72,531 @@ func (db *DB) CreateInBatches(value interface{}, batchSize int) (tx *DB) {
 	return
 }
 
+// UpdateInBatches updates columns of records in values, a slice of records with primary keys, to values of each
+// record with a statement per batch of CreateBatchSize records, all updatable fields except primary keys are
+// updated if no columns specified, update hooks are called for every record, and rows affected of every record
+// are returned by RowsAffectedByRecord
+//
+//	// UPDATE `products` SET `price`=CASE `id` WHEN 1 THEN 10 WHEN 2 THEN 20 ELSE `price` END WHERE `id` IN (1,2)
+//	db.UpdateInBatches(&products, "price")
+//
+// Updates of records with a Version field are conditioned on their versions and increase them, they always run in a
+// transaction, versions of every batch are checked before it's updated, and ErrOptimisticLock is returned and all
+// batches are rolled back if any record has been updated by others, versions of records are only increased after
+// all batches are updated
+func (db *DB) UpdateInBatches(values interface{}, columns ...string) (tx *DB) {
+	tx = db.getInstance()
+	reflectValue := reflect.Indirect(reflect.ValueOf(values))
+	if kind := reflectValue.Kind(); kind != reflect.Slice && kind != reflect.Array {
+		tx.AddError(fmt.Errorf("%w: UpdateInBatches requires a slice of records, got %T", ErrInvalidData, values))
+		return
+	}
+
+	if err := tx.Statement.Parse(values); err != nil {
+		tx.AddError(err)
+		return
+	}
+
+	versionField := lookUpVersionField(tx.Statement.Schema)
+	fields, err := updateInBatchesFields(tx.Statement, columns, versionField)
+	if err != nil {
+		tx.AddError(err)
+		return
+	}
+
+	reflectLen := reflectValue.Len()
+	for i := 0; i < reflectLen; i++ {
+		for _, field := range tx.Statement.Schema.PrimaryFields {
+			if _, isZero := field.ValueOf(tx.Statement.Context, reflect.Indirect(reflectValue.Index(i))); isZero {
+				tx.AddError(fmt.Errorf("%w: record %d of UpdateInBatches", ErrPrimaryKeyRequired, i))
+				return
+			}
+		}
+	}
+
+	batchSize := tx.CreateBatchSize
+	if batchSize <= 0 || batchSize > reflectLen {
+		batchSize = reflectLen
+	}
+
+	var (
+		rowsAffected        int64
+		recordsRowsAffected = make([]int64, reflectLen)
+		recordsVersions     = make([]Version, reflectLen)
+	)
+	callFc := func(tx *DB) error {
+		for i := 0; i < reflectLen; i += batchSize {
+			ends := i + batchSize
+			if ends > reflectLen {
+				ends = reflectLen
+			}
+
+			batch := reflectValue.Slice(i, ends)
+			if !tx.Statement.SkipHooks {
+				now := tx.NowFunc()
+				for _, field := range fields {
+					if field.AutoUpdateTime > 0 {
+						for j := 0; j < batch.Len(); j++ {
+							if err := field.Set(tx.Statement.Context, reflect.Indirect(batch.Index(j)), now); err != nil {
+								return err
+							}
+						}
+					}
+				}
+			}
+
+			// slices share the underlying array, records of values are updated by hooks
+			batchValue := reflect.New(batch.Type())
+			batchValue.Elem().Set(batch)
+
+			subtx := tx.getInstance()
+			subtx.Statement.Dest = batchValue.Interface()
+			subtx.Statement.Model = subtx.Statement.Dest
+
+			set := make(clause.Set, 0, len(fields)+1)
+			for _, field := range fields {
+				set = append(set, clause.Assignment{Column: clause.Column{Name: field.DBName}, Value: updateCase{stmt: subtx.Statement, field: field}})
+			}
+
+			_, primaryValues := schema.GetIdentityFieldValuesMap(subtx.Statement.Context, batch, tx.Statement.Schema.PrimaryFields)
+			column, queryValues := schema.ToQueryValues("", tx.Statement.Schema.PrimaryFieldDBNames, primaryValues)
+
+			var versions []Version
+			if versionField == nil {
+				subtx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: queryValues}}})
+			} else {
+				set = append(set, clause.Assignment{
+					Column: clause.Column{Name: versionField.DBName},
+					Value:  clause.Expr{SQL: "? + 1", Vars: []interface{}{clause.Column{Name: versionField.DBName}}},
+				})
+
+				// every record is conditioned on its own version
+				versions = make([]Version, batch.Len())
+				conds := make([]clause.Expression, batch.Len())
+				for j := 0; j < batch.Len(); j++ {
+					elem := reflect.Indirect(batch.Index(j))
+					exprs := make([]clause.Expression, 0, len(tx.Statement.Schema.PrimaryFields)+1)
+					for _, field := range tx.Statement.Schema.PrimaryFields {
+						value, _ := field.ValueOf(tx.Statement.Context, elem)
+						exprs = append(exprs, clause.Eq{Column: clause.Column{Name: field.DBName}, Value: value})
+					}
+
+					if value, _ := versionField.ValueOf(tx.Statement.Context, elem); value != nil {
+						if version, ok := value.(Version); ok && version.Valid {
+							versions[j] = version
+							exprs = append(exprs, clause.Eq{Column: clause.Column{Name: versionField.DBName}, Value: version.Int64})
+						}
+					}
+					conds[j] = clause.And(exprs...)
+				}
+
+				copy(recordsVersions[i:ends], versions)
+				if len(conds) == 1 {
+					subtx.Statement.AddClause(clause.Where{Exprs: conds})
+				} else {
+					subtx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{clause.Or(conds...)}})
+				}
+			}
+			subtx.Statement.AddClause(set)
+
+			cond := clause.IN{Column: column, Values: queryValues}
+			if versionField != nil && !tx.DryRun {
+				// versions are checked before the update, records updated by others can't be told from the
+				// total rows affected after it
+				if err := checkBatchVersions(tx, batch, i, cond, versionField, versions); err != nil {
+					return err
+				}
+			}
+
+			subtx.callbacks.Update().Execute(subtx)
+			if subtx.Error != nil {
+				return subtx.Error
+			}
+			rowsAffected += subtx.RowsAffected
+
+			if !tx.DryRun {
+				if err := batchRowsAffected(tx, batch, i, cond, versionField, subtx.RowsAffected, recordsRowsAffected[i:ends]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if reflectLen == 0 {
+		return
+	} else if (versionField == nil || tx.DryRun) && (tx.SkipDefaultTransaction || reflectLen <= batchSize) {
+		tx.AddError(callFc(tx.Session(&Session{})))
+	} else {
+		// versioned batches are rolled back together if any of them fails the version check
+		tx.AddError(tx.Transaction(callFc))
+	}
+
+	if versionField != nil && !tx.DryRun {
+		if tx.Error != nil {
+			rowsAffected, recordsRowsAffected = 0, make([]int64, reflectLen)
+		} else {
+			for j, version := range recordsVersions {
+				if version.Valid {
+					tx.AddError(versionField.Set(tx.Statement.Context, reflect.Indirect(reflectValue.Index(j)), Version{Int64: version.Int64 + 1, Valid: true}))
+				}
+			}
+		}
+	}
+
+	tx.RowsAffected = rowsAffected
+	tx.InstanceSet(recordsRowsAffectedKey, recordsRowsAffected)
+	return
+}
+
+const recordsRowsAffectedKey = "gorm:records_rows_affected"
+
+// RowsAffectedByRecord returns rows affected of every record of UpdateInBatches, in the order of the records
+//
+//	tx := db.UpdateInBatches(&products, "price")
+//	for idx, rows := range tx.RowsAffectedByRecord() {
+//		if rows == 0 {
+//			// products[idx] not found
+//		}
+//	}
+func (db *DB) RowsAffectedByRecord() []int64 {
+	if v, ok := db.InstanceGet(recordsRowsAffectedKey); ok {
+		rows, _ := v.([]int64)
+		return rows
+	}
+	return nil
+}
+
+// findBatch finds records of the batch by primary keys, the version field is selected too if not nil
+func findBatch(tx *DB, cond clause.IN, versionField *schema.Field) (map[string]reflect.Value, error) {
+	selects := append([]string{}, tx.Statement.Schema.PrimaryFieldDBNames...)
+	if versionField != nil {
+		selects = append(selects, versionField.DBName)
+	}
+
+	found := reflect.New(reflect.SliceOf(tx.Statement.Schema.ModelType))
+	query := tx.Session(&Session{NewDB: true, SkipHooks: true}).Table(tx.Statement.Table).Select(selects).Where(cond)
+	if tx.Statement.Unscoped {
+		query = query.Unscoped()
+	}
+	if err := query.Find(found.Interface()).Error; err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]reflect.Value, found.Elem().Len())
+	for j := 0; j < found.Elem().Len(); j++ {
+		records[primaryKeyOf(tx.Statement, found.Elem().Index(j))] = found.Elem().Index(j)
+	}
+	return records, nil
+}
+
+// checkBatchVersions returns ErrOptimisticLock if any record of the batch has been updated or deleted by others
+func checkBatchVersions(tx *DB, batch reflect.Value, offset int, cond clause.IN, versionField *schema.Field, versions []Version) error {
+	found, err := findBatch(tx, cond, versionField)
+	if err != nil {
+		return err
+	}
+
+	var conflicts []int
+	for j, version := range versions {
+		if !version.Valid {
+			continue
+		}
+
+		if rv, ok := found[primaryKeyOf(tx.Statement, reflect.Indirect(batch.Index(j)))]; !ok || currentVersion(tx.Statement, versionField, rv) != version {
+			conflicts = append(conflicts, offset+j)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: records %v of UpdateInBatches", ErrOptimisticLock, conflicts)
+	}
+	return nil
+}
+
+// batchRowsAffected sets rows affected of every record of the batch, the statement only reports the total, so
+// records are looked up again if not all of them are updated
+func batchRowsAffected(tx *DB, batch reflect.Value, offset int, cond clause.IN, versionField *schema.Field, total int64, rowsAffected []int64) error {
+	if total < int64(batch.Len()) {
+		if versionField != nil {
+			// updated or deleted by others after versions are checked
+			return fmt.Errorf("%w: records %d to %d of UpdateInBatches", ErrOptimisticLock, offset, offset+batch.Len()-1)
+		}
+
+		found, err := findBatch(tx, cond, nil)
+		if err != nil {
+			return err
+		}
+
+		for j := range rowsAffected {
+			if _, ok := found[primaryKeyOf(tx.Statement, reflect.Indirect(batch.Index(j)))]; ok {
+				rowsAffected[j] = 1
+			}
+		}
+		return nil
+	}
+
+	for j := range rowsAffected {
+		rowsAffected[j] = 1
+	}
+	return nil
+}
+
+func primaryKeyOf(stmt *Statement, rv reflect.Value) string {
+	values := make([]interface{}, len(stmt.Schema.PrimaryFields))
+	for idx, field := range stmt.Schema.PrimaryFields {
+		values[idx], _ = field.ValueOf(stmt.Context, rv)
+	}
+	return utils.ToStringKey(values...)
+}
+
+func currentVersion(stmt *Statement, versionField *schema.Field, rv reflect.Value) Version {
+	if versionField != nil {
+		if value, _ := versionField.ValueOf(stmt.Context, rv); value != nil {
+			version, _ := value.(Version)
+			return version
+		}
+	}
+	return Version{}
+}
+
+// updateInBatchesFields fields updated by UpdateInBatches, including fields updated automatically, the version
+// field is increased separately
+func updateInBatchesFields(stmt *Statement, columns []string, versionField *schema.Field) ([]*schema.Field, error) {
+	if len(stmt.Schema.PrimaryFields) == 0 {
+		return nil, ErrPrimaryKeyRequired
+	}
+
+	var fields []*schema.Field
+	if len(columns) == 0 {
+		for _, dbName := range stmt.Schema.DBNames {
+			if field := stmt.Schema.FieldsByDBName[dbName]; field.Updatable && !field.PrimaryKey && field != versionField {
+				fields = append(fields, field)
+			}
+		}
+		return fields, nil
+	}
+
+	for _, column := range columns {
+		field := stmt.Schema.LookUpField(column)
+		if field == nil || field.DBName == "" || field.PrimaryKey || !field.Updatable || field == versionField {
+			return nil, fmt.Errorf("%w: %s can't be updated by UpdateInBatches", ErrInvalidField, column)
+		}
+		fields = append(fields, field)
+	}
+
+	if !stmt.SkipHooks {
+		for _, dbName := range stmt.Schema.DBNames {
+			if field := stmt.Schema.FieldsByDBName[dbName]; field.AutoUpdateTime > 0 && !utils.Contains(columns, field.Name) && !utils.Contains(columns, field.DBName) {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// updateCase CASE expression setting the column of each record of UpdateInBatches to its own value, values are
+// read when it's built, so changes by BeforeUpdate hooks are updated too
+type updateCase struct {
+	stmt  *Statement
+	field *schema.Field
+}
+
+func (c updateCase) Build(builder clause.Builder) {
+	var (
+		primaryFields = c.stmt.Schema.PrimaryFields
+		reflectValue  = c.stmt.ReflectValue
+	)
+
+	builder.WriteString("CASE")
+	if len(primaryFields) == 1 {
+		builder.WriteByte(' ')
+		builder.WriteQuoted(clause.Column{Name: primaryFields[0].DBName})
+	}
+
+	for i := 0; i < reflectValue.Len(); i++ {
+		elem := reflect.Indirect(reflectValue.Index(i))
+		builder.WriteString(" WHEN ")
+		for idx, field := range primaryFields {
+			value, _ := field.ValueOf(c.stmt.Context, elem)
+			if len(primaryFields) > 1 {
+				if idx > 0 {
+					builder.WriteString(" AND ")
+				}
+				builder.WriteQuoted(clause.Column{Name: field.DBName})
+				builder.WriteString(" = ")
+			}
+			builder.AddVar(builder, value)
+		}
+
+		value, _ := c.field.ValueOf(c.stmt.Context, elem)
+		builder.WriteString(" THEN ")
+		builder.AddVar(builder, value)
+	}
+
+	// the column itself as ELSE to infer types of the values for databases like postgres
+	builder.WriteString(" ELSE ")
+	builder.WriteQuoted(clause.Column{Name: c.field.DBName})
+	builder.WriteString(" END")
+}
+
+// BulkLoad loads rows, a slice of records, into table with the native bulk loading of the dialector if it
+// implements BulkLoadDialector, or falls back to CreateInBatches, table defaults to the table of the records.
+// Hooks and associations are skipped in both ways, batches of the fallback follow CreateBatchSize. Create clauses
+// of fields, e.g. versions and tenants, are applied to records before they are loaded natively, while create
+// callbacks are skipped, so records are loaded by the fallback if a BulkLoadPlugin requires its callbacks. Records
+// mixing blank and set auto increment primary keys are loaded by the fallback too, which inserts DEFAULT for the
+// blank ones
+func (db *DB) BulkLoad(table string, rows interface{}) (tx *DB) {
+	tx = db.getInstance()
+	reflectValue := reflect.Indirect(reflect.ValueOf(rows))
+	if kind := reflectValue.Kind(); kind != reflect.Slice && kind != reflect.Array {
+		tx.AddError(fmt.Errorf("%w: BulkLoad requires a slice of records, got %T", ErrInvalidData, rows))
+		return
+	}
+
+	if err := tx.Statement.Parse(rows); err != nil {
+		tx.AddError(err)
+		return
+	}
+
+	if table == "" {
+		table = tx.Statement.Table
+	}
+	tx.Statement.Table = table
+
+	if reflectValue.Len() == 0 {
+		return
+	}
+
+	if loader, ok := tx.Dialector.(BulkLoadDialector); ok && !tx.DryRun && !requiresCreateCallbacks(tx) {
+		tx.Statement.Dest, tx.Statement.ReflectValue = rows, reflectValue
+		for _, c := range tx.Statement.Schema.CreateClauses {
+			tx.Statement.AddClause(c)
+		}
+		if tx.Error != nil {
+			return
+		}
+
+		if source, ok := newBulkLoadRows(tx.Statement, reflectValue); ok {
+			columns := make([]string, 0, len(source.fields))
+			for _, field := range source.fields {
+				columns = append(columns, field.DBName)
+			}
+
+			rowsAffected, err := loader.BulkLoad(tx, table, columns, source)
+			if !errors.Is(err, ErrNotImplemented) {
+				tx.AddError(err)
+				tx.RowsAffected = rowsAffected
+				return
+			}
+		}
+	}
+
+	batchSize := tx.CreateBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBulkLoadBatchSize
+	}
+
+	result := tx.Session(&Session{SkipHooks: true}).Table(table).Omit(clause.Associations).CreateInBatches(rows, batchSize)
+	tx.AddError(result.Error)
+	tx.RowsAffected = result.RowsAffected
+	return
+}
+
+const defaultBulkLoadBatchSize = 1000
+
+// requiresCreateCallbacks reports whether a BulkLoadPlugin requires its create callbacks for rows of tx
+func requiresCreateCallbacks(tx *DB) bool {
+	for _, plugin := range tx.Plugins {
+		if p, ok := plugin.(BulkLoadPlugin); ok && p.RequiresCreateCallbacks(tx.Statement) {
+			return true
+		}
+	}
+	return false
+}
+
+// bulkLoadRows BulkLoadRows of records, auto increment primary keys are loaded only if set in all records
+type bulkLoadRows struct {
+	stmt         *Statement
+	fields       []*schema.Field
+	reflectValue reflect.Value
+	idx          int
+	now          time.Time
+}
+
+// newBulkLoadRows returns false if some records have auto increment primary keys set and others don't
+func newBulkLoadRows(stmt *Statement, reflectValue reflect.Value) (*bulkLoadRows, bool) {
+	rows := &bulkLoadRows{stmt: stmt, reflectValue: reflectValue, now: stmt.DB.NowFunc()}
+
+	for _, dbName := range stmt.Schema.DBNames {
+		field := stmt.Schema.FieldsByDBName[dbName]
+		if !field.Creatable {
+			continue
+		}
+
+		if field.AutoIncrement || (field == stmt.Schema.PrioritizedPrimaryField && field.HasDefaultValue && field.DefaultValueInterface == nil) {
+			var blanks int
+			for i := 0; i < reflectValue.Len(); i++ {
+				if _, isZero := field.ValueOf(stmt.Context, reflect.Indirect(reflectValue.Index(i))); isZero {
+					blanks++
+				}
+			}
+
+			if blanks == reflectValue.Len() {
+				continue
+			} else if blanks > 0 {
+				return nil, false
+			}
+		}
+		rows.fields = append(rows.fields, field)
+	}
+	return rows, true
+}
+
+func (rows *bulkLoadRows) Next() bool {
+	rows.idx++
+	return rows.idx <= rows.reflectValue.Len()
+}
+
+func (rows *bulkLoadRows) Values() ([]interface{}, error) {
+	var (
+		ctx    = rows.stmt.Context
+		elem   = reflect.Indirect(rows.reflectValue.Index(rows.idx - 1))
+		values = make([]interface{}, len(rows.fields))
+	)
+
+	for idx, field := range rows.fields {
+		value, isZero := field.ValueOf(ctx, elem)
+		if isZero {
+			var err error
+			if field.AutoCreateTime > 0 || field.AutoUpdateTime > 0 {
+				err = field.Set(ctx, elem, rows.now)
+			} else if field.DefaultValueInterface != nil {
+				err = field.Set(ctx, elem, field.DefaultValueInterface)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+			value, _ = field.ValueOf(ctx, elem)
+		}
+
+		if valuer, ok := value.(driver.Valuer); ok {
+			var err error
+			if value, err = valuer.Value(); err != nil {
+				return nil, err
+			}
+		}
+		values[idx] = value
+	}
+	return values, nil
+}
+
+func (rows *bulkLoadRows) Err() error {
+	return nil
+}
+
 // Save updates value in database. If value doesn't contain a matching primary key, value is inserted.
 func (db *DB) Save(value interface{}) (tx *DB) {
 	tx = db.getInstance()
@@ -170,6 +698,65 @@ func (db *DB) Find(dest interface{}, conds ...interface{}) (tx *DB) {
 	return tx.callbacks.Query().Execute(tx)
 }
 
+// Iterate finds all records with a single query and scans them into dest one at a time, dest is a pointer to
+// a struct or map reused for every record, so memory stays flat no matter how many records are found.
+// AfterFind hooks are called before fc, iterating stops when fc returns an error, return ErrStopIteration
+// to stop without error. Preload is not supported as records are not kept
+//
+//	var user User
+//	db.Where("active = ?", true).Iterate(&user, func(tx *gorm.DB) error {
+//		return w.Write([]string{user.Name, user.Email})
+//	})
+func (db *DB) Iterate(dest interface{}, fc func(tx *DB) error) *DB {
+	tx := db.Session(&Session{})
+	if tx.Statement.Model == nil {
+		tx.Statement.Model = dest
+	}
+
+	rows, err := tx.Rows()
+	if err != nil {
+		tx.AddError(err)
+		return tx
+	}
+	defer rows.Close()
+
+	var (
+		rowsAffected int64
+		scanTx       = tx.getInstance()
+	)
+
+	for rows.Next() {
+		if err := scanTx.ScanRows(rows, dest); err != nil {
+			break
+		}
+		rowsAffected++
+
+		if !scanTx.Statement.SkipHooks && scanTx.Statement.Schema != nil && scanTx.Statement.Schema.AfterFind {
+			if i, ok := dest.(interface{ AfterFind(*DB) error }); ok {
+				if scanTx.AddError(i.AfterFind(scanTx.Session(&Session{NewDB: true}))) != nil {
+					break
+				}
+			}
+		}
+
+		if err := fc(scanTx); err != nil {
+			if !errors.Is(err, ErrStopIteration) {
+				tx.AddError(err)
+			}
+			break
+		}
+	}
+
+	if scanTx.Error != nil {
+		tx.AddError(scanTx.Error)
+	} else if err := rows.Err(); err != nil {
+		tx.AddError(err)
+	}
+
+	tx.RowsAffected = rowsAffected
+	return tx
+}
+
 // FindInBatches finds all records in batches of batchSize
 func (db *DB) FindInBatches(dest interface{}, batchSize int, fc func(tx *DB, batch int) error) *DB {
 	var (
@@ -439,6 +1026,104 @@ func (db *DB) Delete(value interface{}, conds ...interface{}) (tx *DB) {
 	return tx.callbacks.Delete().Execute(tx)
 }
 
+// Restore restores soft deleted records of value matching given conditions. If value contains primary key it is
+// included in the conditions. The deleter saved by the `deletedBy` tag is cleared.
+//
+// Soft deleted has one and has many associations of restored records are restored only if they are selected, all
+// of their soft deleted records are restored, including those deleted before the restored records. Run it in a
+// transaction to restore them atomically.
+//
+//	db.Select("Pets").Restore(&user)
+//	db.Select(clause.Associations).Restore(&user)
+func (db *DB) Restore(value interface{}, conds ...interface{}) (tx *DB) {
+	tx = db.getInstance()
+	if err := tx.Statement.Parse(value); err != nil {
+		tx.AddError(err)
+		return
+	}
+
+	field, sd := lookUpSoftDeleteField(tx.Statement.Schema)
+	if field == nil {
+		tx.AddError(fmt.Errorf("%w: %s has no soft delete field", ErrInvalidField, tx.Statement.Schema.Name))
+		return
+	}
+
+	restored := map[string]interface{}{field.DBName: sd.NotDeletedValue(field)}
+	if name, ok := field.TagSettings["DELETEDBY"]; ok {
+		byField := tx.Statement.Schema.LookUpField(name)
+		if byField == nil || byField.DBName == "" {
+			tx.AddError(fmt.Errorf("%w: deleted by field %s not found", ErrInvalidField, name))
+			return
+		}
+		restored[byField.DBName] = reflect.Zero(byField.FieldType).Interface()
+	}
+
+	// selected associations to restore, selects don't apply to the statements of records
+	var cascades []*schema.Relationship
+	for _, rel := range softDeleteCascades(tx.Statement.Schema) {
+		for _, name := range tx.Statement.Selects {
+			if name == rel.Name || name == clause.Associations {
+				cascades = append(cascades, rel)
+				break
+			}
+		}
+	}
+	tx.Statement.Selects = nil
+
+	if len(conds) > 0 {
+		if exprs := tx.Statement.BuildCondition(conds[0], conds[1:]...); len(exprs) > 0 {
+			tx.Statement.AddClause(clause.Where{Exprs: exprs})
+		}
+	}
+	tx.Statement.Clauses[onlyTrashedKey] = clause.Clause{}
+
+	// records to restore associations of, which is value if it's identified by primary keys
+	records := reflect.ValueOf(value)
+	if _, primaryValues := schema.GetIdentityFieldValuesMap(tx.Statement.Context, reflect.Indirect(records), tx.Statement.Schema.PrimaryFields); len(cascades) > 0 && (len(conds) > 0 || len(primaryValues) == 0) {
+		found := reflect.New(reflect.SliceOf(tx.Statement.Schema.ModelType))
+		if err := tx.Session(&Session{}).Find(found.Interface()).Error; err != nil {
+			tx.AddError(err)
+			return
+		}
+		records = found.Elem()
+	}
+
+	tx = tx.Model(value).UpdateColumns(restored)
+	if tx.Error != nil || (reflect.Indirect(records).Kind() == reflect.Slice && reflect.Indirect(records).Len() == 0) {
+		return
+	}
+
+	for _, rel := range cascades {
+		child := reflect.New(rel.FieldSchema.ModelType).Interface()
+		conds := rel.ToQueryConditions(tx.Statement.Context, records)
+		tx.AddError(tx.Session(&Session{NewDB: true}).Restore(child, clause.And(conds...)).Error)
+	}
+	return
+}
+
+// Purge permanently deletes records of the model soft deleted more than olderThan ago in batches, along with soft
+// deleted has one and has many associations of them, all soft deleted records are purged if olderThan is zero.
+// Records still referenced by has one or has many associations that aren't soft deleted are kept.
+//
+//	db.Model(&User{}).Purge(30 * 24 * time.Hour)
+func (db *DB) Purge(olderThan time.Duration) (tx *DB) {
+	tx = db.getInstance()
+	if tx.Statement.Model == nil {
+		tx.AddError(ErrModelValueRequired)
+		return
+	}
+
+	var deletedBefore time.Time
+	if olderThan > 0 {
+		deletedBefore = tx.NowFunc().Add(-olderThan)
+	}
+
+	rowsAffected, err := purgeSoftDeleted(tx, deletedBefore)
+	tx.RowsAffected = rowsAffected
+	tx.AddError(err)
+	return
+}
+
 func (db *DB) Count(count *int64) (tx *DB) {
 	tx = db.getInstance()
 	if tx.Statement.Model == nil {
@@ -527,6 +1212,12 @@ func (db *DB) Scan(dest interface{}) (tx *DB) {
 	currentLogger, newLogger := config.Logger, logger.Recorder.New()
 	config.Logger = newLogger
 
+	tracer, isParamsTracer := currentLogger.(logger.ParamsTracer)
+	recorder := &paramsRecorder{Interface: newLogger, beginAt: newLogger.BeginAt}
+	if isParamsTracer {
+		config.Logger = recorder
+	}
+
 	tx = db.getInstance()
 	tx.Config = &config
 
@@ -540,13 +1231,37 @@ func (db *DB) Scan(dest interface{}) (tx *DB) {
 		tx.AddError(rows.Close())
 	}
 
-	currentLogger.Trace(tx.Statement.Context, newLogger.BeginAt, func() (string, int64) {
-		return newLogger.SQL, tx.RowsAffected
-	}, tx.Error)
+	if isParamsTracer {
+		tracer.TraceParams(tx.Statement.Context, recorder.beginAt, func() (string, []interface{}, int64) {
+			sql, vars := recorder.sql, recorder.vars
+			if filter, ok := currentLogger.(ParamsFilter); ok {
+				sql, vars = filter.ParamsFilter(tx.Statement.Context, sql, vars...)
+			}
+			return sql, vars, tx.RowsAffected
+		}, tx.Error)
+	} else {
+		currentLogger.Trace(tx.Statement.Context, newLogger.BeginAt, func() (string, int64) {
+			return newLogger.SQL, tx.RowsAffected
+		}, tx.Error)
+	}
 	tx.Logger = currentLogger
 	return
 }
 
+// paramsRecorder records SQL with its params for loggers implementing logger.ParamsTracer
+type paramsRecorder struct {
+	logger.Interface
+	beginAt time.Time
+	sql     string
+	vars    []interface{}
+}
+
+// TraceParams implements logger.ParamsTracer interface
+func (r *paramsRecorder) TraceParams(ctx context.Context, begin time.Time, fc func() (string, []interface{}, int64), err error) {
+	r.beginAt = begin
+	r.sql, r.vars, _ = fc()
+}
+
 // Pluck queries a single column from a model, returning in the slice dest. E.g.:
 //
 //	var ages []int64
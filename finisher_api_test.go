@@ -0,0 +1,327 @@
+package gorm_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+// rowsDriver sql driver returning the same rows for all queries, and the same rows affected for all statements,
+// queries are recorded if queries is not nil, which isn't safe for concurrent use
+type rowsDriver struct {
+	columns  []string
+	rows     [][]driver.Value
+	affected int64
+	queries  *[]string
+}
+
+type rowsConn struct{ rowsDriver }
+
+type rowsIterator struct {
+	rowsDriver
+	idx int
+}
+
+func (d rowsDriver) Open(string) (driver.Conn, error) { return rowsConn{d}, nil }
+
+type connector struct{ driver rowsDriver }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open("") }
+func (c connector) Driver() driver.Driver                        { return c.driver }
+
+func (c rowsConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c rowsConn) Close() error                        { return nil }
+
+func (c rowsConn) Begin() (driver.Tx, error) {
+	c.record("BEGIN")
+	return rowsTx(c), nil
+}
+
+func (c rowsConn) record(query string) {
+	if c.queries != nil {
+		*c.queries = append(*c.queries, query)
+	}
+}
+
+type rowsTx rowsConn
+
+func (tx rowsTx) Commit() error   { rowsConn(tx).record("COMMIT"); return nil }
+func (tx rowsTx) Rollback() error { rowsConn(tx).record("ROLLBACK"); return nil }
+
+func (c rowsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.queries != nil {
+		*c.queries = append(*c.queries, query)
+	}
+	return &rowsIterator{rowsDriver: c.rowsDriver}, nil
+}
+
+func (c rowsConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	*c.queries = append(*c.queries, query)
+	return driver.RowsAffected(c.affected), nil
+}
+
+func (r *rowsIterator) Columns() []string { return r.columns }
+func (r *rowsIterator) Close() error      { return nil }
+
+func (r *rowsIterator) Next(dest []driver.Value) error {
+	if r.idx >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.idx])
+	r.idx++
+	return nil
+}
+
+type iterateUser struct {
+	ID    uint
+	Name  string
+	Age   int
+	Found bool `gorm:"-"`
+}
+
+func (u *iterateUser) AfterFind(*gorm.DB) error {
+	u.Found = true
+	return nil
+}
+
+func TestIterate(t *testing.T) {
+	var queries []string
+	sqlDB := sql.OpenDB(connector{rowsDriver{
+		columns: []string{"id", "name", "age"},
+		rows:    [][]driver.Value{{int64(1), "jinzhu", int64(18)}, {int64(2), "hello", nil}, {int64(3), "world", int64(20)}},
+		queries: &queries,
+	}})
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{ConnPool: sqlDB})
+
+	var (
+		user  iterateUser
+		names []string
+	)
+	result := db.Where("age > ?", 1).Iterate(&user, func(tx *gorm.DB) error {
+		if !user.Found {
+			t.Errorf("AfterFind should be called before callback of every row, got %+v", user)
+		}
+		if user.Name == "hello" && user.Age != 0 {
+			t.Errorf("user should be reset for every row, got %+v", user)
+		}
+		names = append(names, user.Name)
+
+		// reset, so the next row is checked by its own AfterFind
+		user.Found = false
+		return nil
+	})
+
+	if result.Error != nil || result.RowsAffected != 3 {
+		t.Fatalf("failed to iterate, got %v, rows affected %v", result.Error, result.RowsAffected)
+	}
+	tests.AssertEqual(t, names, []string{"jinzhu", "hello", "world"})
+	tests.AssertEqual(t, queries, []string{"SELECT * FROM `iterate_users` WHERE age > ?"})
+
+	var ids []uint
+	err := gorm.G[iterateUser](db).Iterate(context.Background(), func(item iterateUser) error {
+		ids = append(ids, item.ID)
+		if len(ids) == 2 {
+			return gorm.ErrStopIteration
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("stopping iteration shouldn't return error, got %v", err)
+	}
+	tests.AssertEqual(t, ids, []uint{1, 2})
+
+	errFailed := errors.New("failed")
+	if err := db.Iterate(&user, func(*gorm.DB) error { return errFailed }).Error; !errors.Is(err, errFailed) {
+		t.Errorf("error of callback should be returned, got %v", err)
+	}
+}
+
+type batchProduct struct {
+	ID        uint
+	Name      string
+	Price     int
+	UpdatedAt time.Time
+	hooked    bool
+}
+
+func (p *batchProduct) BeforeUpdate(*gorm.DB) error {
+	p.hooked = true
+	p.Price *= 10
+	return nil
+}
+
+func TestUpdateInBatches(t *testing.T) {
+	var (
+		now      = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		recorder = &sqlRecorder{Interface: logger.Discard}
+		db, _    = gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, SkipDefaultTransaction: true, CreateBatchSize: 2, Logger: recorder, NowFunc: func() time.Time { return now }})
+		products = []batchProduct{{ID: 1, Name: "a", Price: 1}, {ID: 2, Name: "b", Price: 2}, {ID: 3, Name: "c", Price: 3}}
+	)
+
+	if err := db.UpdateInBatches(&products, "price").Error; err != nil {
+		t.Fatalf("failed to update in batches, got %v", err)
+	}
+
+	tests.AssertEqual(t, recorder.SQLs, []string{
+		"UPDATE `batch_products` SET `price`=CASE `id` WHEN 1 THEN 10 WHEN 2 THEN 20 ELSE `price` END,`updated_at`=CASE `id` WHEN 1 THEN \"2024-01-02 03:04:05\" WHEN 2 THEN \"2024-01-02 03:04:05\" ELSE `updated_at` END WHERE `id` IN (1,2)",
+		"UPDATE `batch_products` SET `price`=CASE `id` WHEN 3 THEN 30 ELSE `price` END,`updated_at`=CASE `id` WHEN 3 THEN \"2024-01-02 03:04:05\" ELSE `updated_at` END WHERE `id` = 3",
+	})
+
+	for _, product := range products {
+		if !product.hooked || !product.UpdatedAt.Equal(now) {
+			t.Errorf("hooks should be called and updated at should be set for every record, got %+v", product)
+		}
+	}
+
+	if err := db.UpdateInBatches(&products, "id").Error; !errors.Is(err, gorm.ErrInvalidField) {
+		t.Errorf("updating primary key should be rejected, got %v", err)
+	}
+
+	if err := db.UpdateInBatches(&[]batchProduct{{Name: "new"}}).Error; !errors.Is(err, gorm.ErrPrimaryKeyRequired) {
+		t.Errorf("updating records without primary key should be rejected, got %v", err)
+	}
+}
+
+func TestUpdateInBatchesRowsAffected(t *testing.T) {
+	var queries []string
+	sqlDB := sql.OpenDB(connector{rowsDriver{columns: []string{"id"}, rows: [][]driver.Value{{int64(1)}, {int64(3)}}, affected: 2, queries: &queries}})
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{ConnPool: sqlDB, SkipDefaultTransaction: true})
+
+	products := []batchProduct{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}}
+	tx := db.UpdateInBatches(&products, "name")
+	if tx.Error != nil || tx.RowsAffected != 2 {
+		t.Fatalf("failed to update in batches, got %v, rows affected %v", tx.Error, tx.RowsAffected)
+	}
+	tests.AssertEqual(t, tx.RowsAffectedByRecord(), []int64{1, 0, 1})
+	tests.AssertEqual(t, queries[1], "SELECT `id` FROM `batch_products` WHERE `id` IN (?,?,?)")
+
+	openVersioned := func(versions ...int64) *gorm.DB {
+		queries = nil
+		rows := make([][]driver.Value, len(versions))
+		for idx, version := range versions {
+			rows[idx] = []driver.Value{int64(idx + 1), version}
+		}
+		sqlDB := sql.OpenDB(connector{rowsDriver{columns: []string{"id", "version"}, rows: rows, affected: int64(len(versions)), queries: &queries}})
+		db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{ConnPool: sqlDB, SkipDefaultTransaction: true})
+		return db
+	}
+
+	versioned := []versionedProduct{{ID: 1, Stock: 10, Version: gorm.Version{Int64: 1, Valid: true}}, {ID: 2, Stock: 20, Version: gorm.Version{Int64: 5, Valid: true}}}
+	tx = openVersioned(1, 5).UpdateInBatches(&versioned, "stock")
+	if tx.Error != nil || tx.RowsAffected != 2 {
+		t.Fatalf("failed to update versioned records in batches, got %v, rows affected %v", tx.Error, tx.RowsAffected)
+	}
+	tests.AssertEqual(t, tx.RowsAffectedByRecord(), []int64{1, 1})
+	tests.AssertEqual(t, queries, []string{
+		"BEGIN",
+		"SELECT `id`,`version` FROM `versioned_products` WHERE `id` IN (?,?)",
+		"UPDATE `versioned_products` SET `stock`=CASE `id` WHEN ? THEN ? WHEN ? THEN ? ELSE `stock` END,`version`=`version` + 1 WHERE ((`id` = ? AND `version` = ?) OR (`id` = ? AND `version` = ?))",
+		"COMMIT",
+	})
+	if versioned[0].Version.Int64 != 2 || versioned[1].Version.Int64 != 6 {
+		t.Errorf("versions of updated records should be increased, got %+v", versioned)
+	}
+
+	// the second record has been updated by others
+	db = openVersioned(2, 7)
+	tx = db.UpdateInBatches(&versioned, "stock")
+	if !errors.Is(tx.Error, gorm.ErrOptimisticLock) {
+		t.Errorf("updating records updated by others should fail, got %v", tx.Error)
+	}
+	tests.AssertEqual(t, tx.RowsAffectedByRecord(), []int64{0, 0})
+	tests.AssertEqual(t, queries, []string{"BEGIN", "SELECT `id`,`version` FROM `versioned_products` WHERE `id` IN (?,?)", "ROLLBACK"})
+	if versioned[0].Version.Int64 != 2 || versioned[1].Version.Int64 != 6 {
+		t.Errorf("versions should be kept if the batch isn't updated, got %+v", versioned)
+	}
+
+	// the second record is updated by others after versions are checked, the first one is rolled back
+	queries = nil
+	sqlDB = sql.OpenDB(connector{rowsDriver{columns: []string{"id", "version"}, rows: [][]driver.Value{{int64(1), int64(2)}, {int64(2), int64(6)}}, affected: 1, queries: &queries}})
+	db, _ = gorm.Open(tests.DummyDialector{}, &gorm.Config{ConnPool: sqlDB, SkipDefaultTransaction: true})
+	tx = db.UpdateInBatches(&versioned, "stock")
+	if !errors.Is(tx.Error, gorm.ErrOptimisticLock) {
+		t.Errorf("partially updated batches should fail, got %v", tx.Error)
+	}
+	tests.AssertEqual(t, tx.RowsAffected, int64(0))
+	tests.AssertEqual(t, tx.RowsAffectedByRecord(), []int64{0, 0})
+	tests.AssertEqual(t, queries[len(queries)-1], "ROLLBACK")
+	if versioned[0].Version.Int64 != 2 || versioned[1].Version.Int64 != 6 {
+		t.Errorf("versions should be kept if the batch is rolled back, got %+v", versioned)
+	}
+
+	if err := db.UpdateInBatches(&versioned, "version").Error; !errors.Is(err, gorm.ErrInvalidField) {
+		t.Errorf("updating version should be rejected, got %v", err)
+	}
+}
+
+type bulkProduct struct {
+	ID        uint
+	Name      string
+	Price     int `gorm:"default:100"`
+	CreatedAt time.Time
+}
+
+func TestBulkLoad(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	dialector := &tests.BulkLoadDialector{}
+	db, _ := gorm.Open(dialector, &gorm.Config{SkipDefaultTransaction: true, NowFunc: func() time.Time { return now }})
+
+	products := []bulkProduct{{Name: "a", Price: 1}, {Name: "b"}}
+	result := db.BulkLoad("", &products)
+	if result.Error != nil || result.RowsAffected != 2 {
+		t.Fatalf("failed to bulk load, got %v, rows affected %v", result.Error, result.RowsAffected)
+	}
+
+	tests.AssertEqual(t, dialector.Columns["bulk_products"], []string{"name", "price", "created_at"})
+	tests.AssertEqual(t, dialector.Rows["bulk_products"], [][]interface{}{{"a", 1, now}, {"b", 100, now}})
+
+	products = []bulkProduct{{ID: 5, Name: "c", Price: 1}, {ID: 6, Name: "d", Price: 2}}
+	if err := db.BulkLoad("keyed_products", &products).Error; err != nil {
+		t.Fatalf("failed to bulk load records with primary keys, got %v", err)
+	}
+	tests.AssertEqual(t, dialector.Columns["keyed_products"], []string{"id", "name", "price", "created_at"})
+
+	versioned := []versionedProduct{{Name: "a"}, {Name: "b", Version: gorm.Version{Int64: 3, Valid: true}}}
+	if err := db.BulkLoad("", &versioned).Error; err != nil {
+		t.Fatalf("failed to bulk load versioned records, got %v", err)
+	}
+	tests.AssertEqual(t, dialector.Rows["versioned_products"], [][]interface{}{{"a", 0, int64(1)}, {"b", 0, int64(3)}})
+
+	var queries []string
+	sqlDB := sql.OpenDB(connector{rowsDriver{columns: []string{"id"}, rows: [][]driver.Value{{int64(1)}, {int64(2)}}, queries: &queries}})
+	fallback := &tests.BulkLoadDialector{Unsupported: true}
+	db, _ = gorm.Open(fallback, &gorm.Config{ConnPool: sqlDB, SkipDefaultTransaction: true, NowFunc: func() time.Time { return now }})
+
+	products = []bulkProduct{{Name: "a", Price: 1}, {Name: "b"}}
+	if err := db.BulkLoad("archived_products", &products).Error; err != nil {
+		t.Fatalf("failed to bulk load with fallback, got %v", err)
+	}
+
+	tests.AssertEqual(t, queries, []string{"INSERT INTO `archived_products` (`name`,`price`,`created_at`) VALUES (?,?,?),(?,?,?) RETURNING `id`"})
+	if products[0].ID != 1 || products[1].ID != 2 {
+		t.Errorf("primary keys should be backfilled, got %+v", products)
+	}
+
+	// records mixing blank and set primary keys are loaded by the fallback
+	queries = nil
+	mixed := &tests.BulkLoadDialector{}
+	db, _ = gorm.Open(mixed, &gorm.Config{ConnPool: sqlDB, SkipDefaultTransaction: true, NowFunc: func() time.Time { return now }})
+
+	products = []bulkProduct{{ID: 7, Name: "e"}, {Name: "f"}}
+	if err := db.BulkLoad("", &products).Error; err != nil {
+		t.Fatalf("failed to bulk load records mixing primary keys, got %v", err)
+	}
+
+	if len(mixed.Rows) != 0 {
+		t.Errorf("records mixing primary keys should not be loaded natively, got %v", mixed.Rows)
+	}
+	tests.AssertEqual(t, queries, []string{"INSERT INTO `bulk_products` (`name`,`price`,`created_at`,`id`) VALUES (?,?,?,?),(?,?,?,DEFAULT) RETURNING `id`"})
+}
@@ -0,0 +1,286 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm/clause"
+)
+
+// Interface typed query builder for model T, built on top of *DB, see G
+type Interface[T any] interface {
+	Table(name string, args ...interface{}) Interface[T]
+	Clauses(conds ...clause.Expression) Interface[T]
+	Scopes(funcs ...func(*DB) *DB) Interface[T]
+	Where(query interface{}, args ...interface{}) Interface[T]
+	Not(query interface{}, args ...interface{}) Interface[T]
+	Or(query interface{}, args ...interface{}) Interface[T]
+	Select(query interface{}, args ...interface{}) Interface[T]
+	Omit(columns ...string) Interface[T]
+	Distinct(args ...interface{}) Interface[T]
+	Joins(query string, args ...interface{}) Interface[T]
+	InnerJoins(query string, args ...interface{}) Interface[T]
+	Preload(query string, args ...interface{}) Interface[T]
+	Group(name string) Interface[T]
+	Having(query interface{}, args ...interface{}) Interface[T]
+	Order(value interface{}) Interface[T]
+	Limit(limit int) Interface[T]
+	Offset(offset int) Interface[T]
+	Unscoped() Interface[T]
+
+	First(ctx context.Context) (T, error)
+	Last(ctx context.Context) (T, error)
+	Take(ctx context.Context) (T, error)
+	Find(ctx context.Context) ([]T, error)
+	FindInBatches(ctx context.Context, batchSize int, fc func(data []T, batch int) error) error
+	Iterate(ctx context.Context, fc func(item T) error) error
+	Count(ctx context.Context, column string) (int64, error)
+	Scan(ctx context.Context, dest interface{}) error
+	Row(ctx context.Context) *sql.Row
+	Rows(ctx context.Context) (*sql.Rows, error)
+
+	Create(ctx context.Context, value *T) error
+	CreateInBatches(ctx context.Context, values *[]T, batchSize int) error
+	Update(ctx context.Context, column string, value interface{}) (rowsAffected int64, err error)
+	Updates(ctx context.Context, value T) (rowsAffected int64, err error)
+	Delete(ctx context.Context) (rowsAffected int64, err error)
+}
+
+// G returns a typed query builder for model T, chained conditions are applied lazily to a session of db
+// when a finisher method is called, so a builder could be reused safely
+//
+//	user, err := gorm.G[User](db).Where("name = ?", "jinzhu").First(ctx)
+//	users, err := gorm.G[User](db).Where("age > ?", 18).Order("id").Find(ctx)
+//	rows, err := gorm.G[User](db).Where("id = ?", user.ID).Update(ctx, "name", "hello")
+func G[T any](db *DB, opts ...clause.Expression) Interface[T] {
+	g := generic[T]{db: db}
+	if len(opts) > 0 {
+		g = g.with(func(tx *DB) *DB {
+			return tx.Clauses(opts...)
+		})
+	}
+	return g
+}
+
+type generic[T any] struct {
+	db  *DB
+	ops []func(*DB) *DB
+}
+
+func (g generic[T]) with(op func(*DB) *DB) generic[T] {
+	ops := make([]func(*DB) *DB, len(g.ops), len(g.ops)+1)
+	copy(ops, g.ops)
+	return generic[T]{db: g.db, ops: append(ops, op)}
+}
+
+func (g generic[T]) apply(ctx context.Context) *DB {
+	tx := g.db.Session(&Session{Context: ctx})
+	for _, op := range g.ops {
+		tx = op(tx)
+	}
+	return tx
+}
+
+func (g generic[T]) Table(name string, args ...interface{}) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Table(name, args...)
+	})
+}
+
+func (g generic[T]) Clauses(conds ...clause.Expression) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Clauses(conds...)
+	})
+}
+
+func (g generic[T]) Scopes(funcs ...func(*DB) *DB) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Scopes(funcs...)
+	})
+}
+
+func (g generic[T]) Where(query interface{}, args ...interface{}) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Where(query, args...)
+	})
+}
+
+func (g generic[T]) Not(query interface{}, args ...interface{}) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Not(query, args...)
+	})
+}
+
+func (g generic[T]) Or(query interface{}, args ...interface{}) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Or(query, args...)
+	})
+}
+
+func (g generic[T]) Select(query interface{}, args ...interface{}) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Select(query, args...)
+	})
+}
+
+func (g generic[T]) Omit(columns ...string) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Omit(columns...)
+	})
+}
+
+func (g generic[T]) Distinct(args ...interface{}) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Distinct(args...)
+	})
+}
+
+func (g generic[T]) Joins(query string, args ...interface{}) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Joins(query, args...)
+	})
+}
+
+func (g generic[T]) InnerJoins(query string, args ...interface{}) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.InnerJoins(query, args...)
+	})
+}
+
+func (g generic[T]) Preload(query string, args ...interface{}) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Preload(query, args...)
+	})
+}
+
+func (g generic[T]) Group(name string) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Group(name)
+	})
+}
+
+func (g generic[T]) Having(query interface{}, args ...interface{}) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Having(query, args...)
+	})
+}
+
+func (g generic[T]) Order(value interface{}) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Order(value)
+	})
+}
+
+func (g generic[T]) Limit(limit int) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Limit(limit)
+	})
+}
+
+func (g generic[T]) Offset(offset int) Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Offset(offset)
+	})
+}
+
+func (g generic[T]) Unscoped() Interface[T] {
+	return g.with(func(tx *DB) *DB {
+		return tx.Unscoped()
+	})
+}
+
+// First finds the first record ordered by primary key
+func (g generic[T]) First(ctx context.Context) (T, error) {
+	var result T
+	err := g.apply(ctx).First(&result).Error
+	return result, err
+}
+
+// Last finds the last record ordered by primary key
+func (g generic[T]) Last(ctx context.Context) (T, error) {
+	var result T
+	err := g.apply(ctx).Last(&result).Error
+	return result, err
+}
+
+// Take finds the first record returned by the database in no specified order
+func (g generic[T]) Take(ctx context.Context) (T, error) {
+	var result T
+	err := g.apply(ctx).Take(&result).Error
+	return result, err
+}
+
+// Find finds all matching records
+func (g generic[T]) Find(ctx context.Context) ([]T, error) {
+	var results []T
+	err := g.apply(ctx).Find(&results).Error
+	return results, err
+}
+
+// FindInBatches finds all matching records in batches of batchSize
+func (g generic[T]) FindInBatches(ctx context.Context, batchSize int, fc func(data []T, batch int) error) error {
+	var results []T
+	return g.apply(ctx).FindInBatches(&results, batchSize, func(tx *DB, batch int) error {
+		return fc(results, batch)
+	}).Error
+}
+
+// Iterate iterates matching records one at a time with a single query, see DB.Iterate
+func (g generic[T]) Iterate(ctx context.Context, fc func(item T) error) error {
+	var item T
+	return g.apply(ctx).Iterate(&item, func(tx *DB) error {
+		return fc(item)
+	}).Error
+}
+
+// Count counts matching records, column could be blank to count all rows
+func (g generic[T]) Count(ctx context.Context, column string) (int64, error) {
+	var count int64
+	tx := g.apply(ctx).Model(new(T))
+	if column != "" && column != "*" {
+		tx = tx.Select(column)
+	}
+	err := tx.Count(&count).Error
+	return count, err
+}
+
+// Scan scans selected values into dest
+func (g generic[T]) Scan(ctx context.Context, dest interface{}) error {
+	return g.apply(ctx).Model(new(T)).Scan(dest).Error
+}
+
+func (g generic[T]) Row(ctx context.Context) *sql.Row {
+	return g.apply(ctx).Model(new(T)).Row()
+}
+
+func (g generic[T]) Rows(ctx context.Context) (*sql.Rows, error) {
+	return g.apply(ctx).Model(new(T)).Rows()
+}
+
+// Create inserts value, the value's primary key is back filled
+func (g generic[T]) Create(ctx context.Context, value *T) error {
+	return g.apply(ctx).Create(value).Error
+}
+
+// CreateInBatches inserts values in batches of batchSize
+func (g generic[T]) CreateInBatches(ctx context.Context, values *[]T, batchSize int) error {
+	return g.apply(ctx).CreateInBatches(values, batchSize).Error
+}
+
+// Update updates column with value for matching records
+func (g generic[T]) Update(ctx context.Context, column string, value interface{}) (int64, error) {
+	tx := g.apply(ctx).Model(new(T)).Update(column, value)
+	return tx.RowsAffected, tx.Error
+}
+
+// Updates updates non-zero fields of value, value's primary key is used as condition if not blank
+func (g generic[T]) Updates(ctx context.Context, value T) (int64, error) {
+	tx := g.apply(ctx).Model(&value).Updates(&value)
+	return tx.RowsAffected, tx.Error
+}
+
+// Delete deletes matching records, performs a soft delete if T has a soft delete field
+func (g generic[T]) Delete(ctx context.Context) (int64, error) {
+	tx := g.apply(ctx).Delete(new(T))
+	return tx.RowsAffected, tx.Error
+}
@@ -0,0 +1,47 @@
+package gorm_test
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+func TestGenericsSQL(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		recorder = logger.Recorder.New()
+		db, _    = gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: recorder})
+	)
+
+	query := gorm.G[tests.User](db).Where("name = ?", "jinzhu")
+
+	if _, err := query.First(ctx); err != nil {
+		t.Fatalf("failed to build first, got %v", err)
+	}
+	tests.AssertEqual(t, recorder.SQL, "SELECT * FROM `users` WHERE name = \"jinzhu\" AND `users`.`deleted_at` IS NULL ORDER BY `users`.`id` LIMIT 1")
+
+	if _, err := query.Where("age > ?", 18).Order("age desc").Find(ctx); err != nil {
+		t.Fatalf("failed to build find, got %v", err)
+	}
+	tests.AssertEqual(t, recorder.SQL, "SELECT * FROM `users` WHERE name = \"jinzhu\" AND age > 18 AND `users`.`deleted_at` IS NULL ORDER BY age desc")
+
+	// the base query should not be affected by chained conditions
+	if _, err := query.Count(ctx, ""); err != nil {
+		t.Fatalf("failed to build count, got %v", err)
+	}
+	tests.AssertEqual(t, recorder.SQL, "SELECT count(*) FROM `users` WHERE name = \"jinzhu\" AND `users`.`deleted_at` IS NULL")
+
+	if _, err := query.Update(ctx, "age", 20); err != nil {
+		t.Fatalf("failed to build update, got %v", err)
+	}
+	if _, err := query.Delete(ctx); err != nil {
+		t.Fatalf("failed to build delete, got %v", err)
+	}
+
+	if _, err := gorm.G[tests.User](db).Delete(ctx); err != gorm.ErrMissingWhereClause {
+		t.Errorf("delete without conditions should be rejected, got %v", err)
+	}
+}
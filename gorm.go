@@ -34,6 +34,11 @@ type Config struct {
 	DryRun bool
 	// PrepareStmt executes the given query in cached statement
 	PrepareStmt bool
+	// PrepareStmtMaxSize max number of cached prepared statements, least recently used statements are closed
+	// beyond it, default to DefaultStmtCacheSize if zero, unlimited if negative
+	PrepareStmtMaxSize int
+	// PrepareStmtTTL cached prepared statements are closed after unused for it, never expire if not positive
+	PrepareStmtTTL time.Duration
 	// DisableAutomaticPing
 	DisableAutomaticPing bool
 	// DisableForeignKeyConstraintWhenMigrating
@@ -52,6 +57,13 @@ type Config struct {
 	TranslateError bool
 	// PropagateUnscoped propagate Unscoped to every other nested statement
 	PropagateUnscoped bool
+	// MaxPageSize max page size of PageLimit and FindPage, default to 50
+	MaxPageSize int
+	// ConcurrentFindPage run count and data queries of FindPage concurrently when not in a transaction
+	ConcurrentFindPage bool
+	// CursorSigningKey key to sign pagination cursors, which is required by Paginate, use the same key in processes
+	// sharing cursors
+	CursorSigningKey []byte
 
 	// ClauseBuilders clause builder
 	ClauseBuilders map[string]clause.ClauseBuilder
@@ -161,6 +173,10 @@ func Open(dialector Dialector, opts ...Option) (db *DB, err error) {
 		config.NowFunc = func() time.Time { return time.Now().Local() }
 	}
 
+	if config.MaxPageSize <= 0 {
+		config.MaxPageSize = defaultMaxPageSize
+	}
+
 	if dialector != nil {
 		config.Dialector = dialector
 	}
@@ -192,7 +208,7 @@ func Open(dialector Dialector, opts ...Option) (db *DB, err error) {
 	}
 
 	if config.PrepareStmt {
-		preparedStmt := NewPreparedStmtDB(db.ConnPool)
+		preparedStmt := newPreparedStmtDB(db.ConnPool, config.PrepareStmtMaxSize, config.PrepareStmtTTL)
 		db.cacheStore.Store(preparedStmtDBKey, preparedStmt)
 		db.ConnPool = preparedStmt
 	}
@@ -263,7 +279,7 @@ func (db *DB) Session(config *Session) *DB {
 		if v, ok := db.cacheStore.Load(preparedStmtDBKey); ok {
 			preparedStmt = v.(*PreparedStmtDB)
 		} else {
-			preparedStmt = NewPreparedStmtDB(db.ConnPool)
+			preparedStmt = newPreparedStmtDB(db.ConnPool, db.PrepareStmtMaxSize, db.PrepareStmtTTL)
 			db.cacheStore.Store(preparedStmtDBKey, preparedStmt)
 		}
 
@@ -278,6 +294,8 @@ func (db *DB) Session(config *Session) *DB {
 				ConnPool: db.Config.ConnPool,
 				Mux:      preparedStmt.Mux,
 				Stmts:    preparedStmt.Stmts,
+				maxSize:  preparedStmt.maxSize,
+				ttl:      preparedStmt.ttl,
 			}
 		}
 		txConfig.ConnPool = tx.Statement.ConnPool
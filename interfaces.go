@@ -26,6 +26,13 @@ type Plugin interface {
 	Initialize(*DB) error
 }
 
+// ExplainDialector dialector supporting EXPLAIN, used to capture query plans of slow queries
+type ExplainDialector interface {
+	// ExplainStatement returns the statement explaining sql, which executes sql if analyze is true,
+	// e.g. `EXPLAIN ANALYZE <sql>`
+	ExplainStatement(sql string, analyze bool) string
+}
+
 type ParamsFilter interface {
 	ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{})
 }
@@ -87,6 +94,31 @@ type Rows interface {
 	Close() error
 }
 
+// CollateDialector dialector renders collation aware expressions used by ORDER BY, e.g. CONVERT(`name` USING gbk)
+type CollateDialector interface {
+	Collate(column clause.Column, collation string) clause.Expression
+}
+
+// BulkLoadDialector dialector loads rows into table natively, e.g. COPY of PostgreSQL or LOAD DATA LOCAL of MySQL,
+// returns ErrNotImplemented to fall back to CreateInBatches
+type BulkLoadDialector interface {
+	BulkLoad(tx *DB, table string, columns []string, rows BulkLoadRows) (rowsAffected int64, err error)
+}
+
+// BulkLoadPlugin plugin whose create callbacks must run for rows of BulkLoad, e.g. to shard or audit them, rows
+// of statements it requires create callbacks for are loaded by CreateInBatches rather than natively
+type BulkLoadPlugin interface {
+	Plugin
+	RequiresCreateCallbacks(stmt *Statement) bool
+}
+
+// BulkLoadRows rows of BulkLoad, Values returns driver values of the current row in the order of columns
+type BulkLoadRows interface {
+	Next() bool
+	Values() ([]interface{}, error)
+	Err() error
+}
+
 type ErrorTranslator interface {
 	Translate(err error) error
 }
@@ -169,6 +169,9 @@ func (l *logger) Trace(ctx context.Context, begin time.Time, fc func() (string,
 	case elapsed > l.SlowThreshold && l.SlowThreshold != 0 && l.LogLevel >= Warn:
 		sql, rows := fc()
 		slowLog := fmt.Sprintf("SLOW SQL >= %v", l.SlowThreshold)
+		if plan := QueryPlanFromContext(ctx); plan != "" {
+			sql += "\n" + plan
+		}
 		if rows == -1 {
 			l.Printf(l.traceWarnStr, utils.FileWithLineNum(), slowLog, float64(elapsed.Nanoseconds())/1e6, "-", sql)
 		} else {
@@ -192,6 +195,22 @@ func (l *logger) ParamsFilter(ctx context.Context, sql string, params ...interfa
 	return sql, params
 }
 
+type queryPlanKey struct{}
+
+// WithQueryPlan returns a context with the query plan of the traced statement, which is included in slow SQL logs
+func WithQueryPlan(ctx context.Context, plan string) context.Context {
+	return context.WithValue(ctx, queryPlanKey{}, plan)
+}
+
+// QueryPlanFromContext returns the query plan set by WithQueryPlan
+func QueryPlanFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	plan, _ := ctx.Value(queryPlanKey{}).(string)
+	return plan
+}
+
 type traceRecorder struct {
 	Interface
 	BeginAt      time.Time
@@ -0,0 +1,268 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm/utils"
+)
+
+// ParamsTracer logger traces SQL with placeholders and its params separately, gorm calls TraceParams
+// instead of Trace for loggers implementing it
+type ParamsTracer interface {
+	TraceParams(ctx context.Context, begin time.Time, fc func() (sql string, params []interface{}, rowsAffected int64), err error)
+}
+
+// Field key value field of structured log records
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Record structured log record, the fields of SQL records are sql, vars, rows, duration, caller, error,
+// slow_threshold, plan and trace_id, fields without values are omitted
+type Record struct {
+	Time    time.Time
+	Level   LogLevel
+	Message string
+	Fields  []Field
+}
+
+// Handler handles structured log records, e.g. writes them as JSON or passes them to another logging library
+type Handler interface {
+	Handle(ctx context.Context, record Record) error
+}
+
+// HandlerFunc function of Handler
+type HandlerFunc func(ctx context.Context, record Record) error
+
+// Handle implements Handler interface
+func (f HandlerFunc) Handle(ctx context.Context, record Record) error {
+	return f(ctx, record)
+}
+
+type traceIDKey struct{}
+
+// WithTraceID returns a context with trace or request ID, which is logged by the structured logger
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext returns the trace ID set by WithTraceID
+func TraceIDFromContext(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
+
+// StructuredConfig structured logger config
+type StructuredConfig struct {
+	Config
+	// TraceID returns trace or request ID from the context, default to TraceIDFromContext
+	TraceID func(ctx context.Context) string
+}
+
+// NewStructured initialize structured logger, which logs records with separate fields to the handler,
+// Colorful is ignored, params are redacted if ParameterizedQueries is set
+//
+//	db, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{
+//		Logger: logger.NewStructured(logger.NewJSONHandler(os.Stdout), logger.StructuredConfig{
+//			Config: logger.Config{SlowThreshold: 200 * time.Millisecond, LogLevel: logger.Warn},
+//		}),
+//	})
+func NewStructured(handler Handler, config StructuredConfig) Interface {
+	if config.TraceID == nil {
+		config.TraceID = TraceIDFromContext
+	}
+	return &structuredLogger{handler: handler, StructuredConfig: config}
+}
+
+type structuredLogger struct {
+	StructuredConfig
+	handler Handler
+}
+
+// LogMode log mode
+func (l *structuredLogger) LogMode(level LogLevel) Interface {
+	newlogger := *l
+	newlogger.LogLevel = level
+	return &newlogger
+}
+
+// Info print info
+func (l *structuredLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel >= Info {
+		l.log(ctx, Info, fmt.Sprintf(msg, data...), Field{Key: "caller", Value: utils.FileWithLineNum()})
+	}
+}
+
+// Warn print warn messages
+func (l *structuredLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel >= Warn {
+		l.log(ctx, Warn, fmt.Sprintf(msg, data...), Field{Key: "caller", Value: utils.FileWithLineNum()})
+	}
+}
+
+// Error print error messages
+func (l *structuredLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel >= Error {
+		l.log(ctx, Error, fmt.Sprintf(msg, data...), Field{Key: "caller", Value: utils.FileWithLineNum()})
+	}
+}
+
+// Trace print sql message, the sql has params inlined already unless they are filtered by ParamsFilter, so vars are
+// not logged
+func (l *structuredLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.TraceParams(ctx, begin, func() (string, []interface{}, int64) {
+		sql, rows := fc()
+		return sql, nil, rows
+	}, err)
+}
+
+// TraceParams print sql message with params
+func (l *structuredLogger) TraceParams(ctx context.Context, begin time.Time, fc func() (string, []interface{}, int64), err error) {
+	if l.LogLevel <= Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.LogLevel >= Error && (!errors.Is(err, ErrRecordNotFound) || !l.IgnoreRecordNotFoundError):
+		l.logSQL(ctx, Error, "sql error", elapsed, fc, Field{Key: "error", Value: err.Error()})
+	case elapsed > l.SlowThreshold && l.SlowThreshold != 0 && l.LogLevel >= Warn:
+		fields := []Field{{Key: "slow_threshold", Value: l.SlowThreshold}}
+		if plan := QueryPlanFromContext(ctx); plan != "" {
+			fields = append(fields, Field{Key: "plan", Value: plan})
+		}
+		l.logSQL(ctx, Warn, "slow sql", elapsed, fc, fields...)
+	case l.LogLevel == Info:
+		l.logSQL(ctx, Info, "sql", elapsed, fc)
+	}
+}
+
+// ParamsFilter filter params
+func (l *structuredLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.Config.ParameterizedQueries {
+		return sql, nil
+	}
+	return sql, params
+}
+
+func (l *structuredLogger) logSQL(ctx context.Context, level LogLevel, msg string, elapsed time.Duration, fc func() (string, []interface{}, int64), fields ...Field) {
+	sql, vars, rows := fc()
+	fields = append([]Field{{Key: "sql", Value: sql}}, fields...)
+	if len(vars) > 0 && !l.ParameterizedQueries {
+		fields = append(fields, Field{Key: "vars", Value: logVars(vars)})
+	}
+	if rows != -1 {
+		fields = append(fields, Field{Key: "rows", Value: rows})
+	}
+	fields = append(fields, Field{Key: "duration", Value: elapsed}, Field{Key: "caller", Value: utils.FileWithLineNum()})
+	l.log(ctx, level, msg, fields...)
+}
+
+func (l *structuredLogger) log(ctx context.Context, level LogLevel, msg string, fields ...Field) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if traceID := l.TraceID(ctx); traceID != "" {
+		fields = append(fields, Field{Key: "trace_id", Value: traceID})
+	}
+
+	if l.handler != nil {
+		_ = l.handler.Handle(ctx, Record{Time: time.Now(), Level: level, Message: msg, Fields: fields})
+	}
+}
+
+// logVars converts params to loggable values, driver.Valuer are resolved and printable bytes are converted to strings
+func logVars(vars []interface{}) []interface{} {
+	values := make([]interface{}, len(vars))
+	for idx, v := range vars {
+		if valuer, ok := v.(driver.Valuer); ok {
+			if value, err := valuer.Value(); err == nil {
+				v = value
+			}
+		}
+
+		if bs, ok := v.([]byte); ok {
+			if utf8.Valid(bs) && isPrintable(string(bs)) {
+				v = string(bs)
+			} else {
+				v = "<binary>"
+			}
+		}
+		values[idx] = v
+	}
+	return values
+}
+
+// NewJSONHandler returns a handler writing records as JSON lines to w, like
+//
+//	{"time":"2024-01-02T03:04:05.006Z","level":"info","msg":"sql","sql":"SELECT * FROM `users` WHERE id = ?","vars":[1],"rows":1,"duration":0.512,"caller":"main.go:12"}
+//
+// durations are written as milliseconds
+func NewJSONHandler(w io.Writer) Handler {
+	return &jsonHandler{w: w}
+}
+
+type jsonHandler struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Handle implements Handler interface
+func (h *jsonHandler) Handle(ctx context.Context, record Record) error {
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	writeJSON(&buf, record.Time.Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSON(&buf, levelName(record.Level))
+	buf.WriteString(`,"msg":`)
+	writeJSON(&buf, record.Message)
+
+	for _, field := range record.Fields {
+		buf.WriteByte(',')
+		writeJSON(&buf, field.Key)
+		buf.WriteByte(':')
+		if d, ok := field.Value.(time.Duration); ok {
+			writeJSON(&buf, float64(d.Nanoseconds())/1e6)
+		} else {
+			writeJSON(&buf, field.Value)
+		}
+	}
+	buf.WriteString("}\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		bs, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(bs)
+}
+
+func levelName(level LogLevel) string {
+	switch level {
+	case Error:
+		return "error"
+	case Warn:
+		return "warn"
+	case Info:
+		return "info"
+	default:
+		return "silent"
+	}
+}
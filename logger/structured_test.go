@@ -0,0 +1,93 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+func TestStructuredLogger(t *testing.T) {
+	var buf bytes.Buffer
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{
+		DryRun: true,
+		Logger: logger.NewStructured(logger.NewJSONHandler(&buf), logger.StructuredConfig{Config: logger.Config{LogLevel: logger.Info}}),
+	})
+
+	var users []tests.User
+	db.WithContext(logger.WithTraceID(context.Background(), "req-1")).Where("name = ? AND age > ?", "jinzhu", 18).Find(&users)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to decode log %q, got %v", buf.String(), err)
+	}
+
+	for key, value := range map[string]interface{}{
+		"level":    "info",
+		"msg":      "sql",
+		"sql":      "SELECT * FROM `users` WHERE (name = ? AND age > ?) AND `users`.`deleted_at` IS NULL",
+		"vars":     []interface{}{"jinzhu", float64(18)},
+		"trace_id": "req-1",
+	} {
+		if !reflect.DeepEqual(record[key], value) {
+			t.Errorf("%v should be %#v, got %#v", key, value, record[key])
+		}
+	}
+
+	if caller, _ := record["caller"].(string); !strings.Contains(caller, "structured_test.go") {
+		t.Errorf("caller should be the test file, got %v", record["caller"])
+	}
+
+	if _, ok := record["duration"].(float64); !ok {
+		t.Errorf("duration should be logged in milliseconds, got %v", record["duration"])
+	}
+
+	var records []logger.Record
+	l := logger.NewStructured(logger.HandlerFunc(func(ctx context.Context, record logger.Record) error {
+		records = append(records, record)
+		return nil
+	}), logger.StructuredConfig{
+		Config:  logger.Config{LogLevel: logger.Warn, ParameterizedQueries: true, SlowThreshold: time.Millisecond},
+		TraceID: func(context.Context) string { return "" },
+	})
+
+	tracer := l.(logger.ParamsTracer)
+	tracer.TraceParams(context.Background(), time.Now(), func() (string, []interface{}, int64) {
+		return "SELECT * FROM users WHERE name = ?", []interface{}{"jinzhu"}, -1
+	}, errors.New("failed"))
+	tracer.TraceParams(context.Background(), time.Now().Add(-time.Second), func() (string, []interface{}, int64) {
+		return "SELECT * FROM users", nil, 2
+	}, nil)
+	tracer.TraceParams(context.Background(), time.Now(), func() (string, []interface{}, int64) {
+		t.Errorf("fast queries shouldn't be logged at warn level")
+		return "", nil, 0
+	}, nil)
+
+	if len(records) != 2 {
+		t.Fatalf("expects 2 records, got %+v", records)
+	}
+
+	fields := func(record logger.Record) map[string]interface{} {
+		m := map[string]interface{}{}
+		for _, field := range record.Fields {
+			m[field.Key] = field.Value
+		}
+		return m
+	}
+
+	if errRecord := fields(records[0]); records[0].Level != logger.Error || errRecord["error"] != "failed" || errRecord["vars"] != nil || errRecord["rows"] != nil {
+		t.Errorf("params should be redacted from error record, got %+v", records[0])
+	}
+
+	if slowRecord := fields(records[1]); records[1].Level != logger.Warn || slowRecord["rows"] != int64(2) || slowRecord["slow_threshold"] != time.Millisecond {
+		t.Errorf("unexpected slow sql record %+v", records[1])
+	}
+}
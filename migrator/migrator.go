@@ -52,6 +52,14 @@ func (l *printSQLLogger) Trace(ctx context.Context, begin time.Time, fc func() (
 	l.Interface.Trace(ctx, begin, fc, err)
 }
 
+// ParamsFilter filters params with the wrapped logger, so its ParameterizedQueries is honoured
+func (l *printSQLLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if filter, ok := l.Interface.(gorm.ParamsFilter); ok {
+		return filter.ParamsFilter(ctx, sql, params...)
+	}
+	return sql, params
+}
+
 // GormDataTypeInterface gorm data type interface
 type GormDataTypeInterface interface {
 	GormDBDataType(*gorm.DB, *schema.Field) string
@@ -0,0 +1,30 @@
+package migrator_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/utils/tests"
+)
+
+func TestDryRunParameterizedQueries(t *testing.T) {
+	var buf bytes.Buffer
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{
+		DryRun: true,
+		Logger: logger.NewStructured(logger.NewJSONHandler(&buf), logger.StructuredConfig{Config: logger.Config{LogLevel: logger.Info, ParameterizedQueries: true}}),
+	})
+
+	m := migrator.Migrator{Config: migrator.Config{DB: db, Dialector: db.Dialector}}
+	_, execTx := m.GetQueryAndExecTx()
+	if err := execTx.Exec("UPDATE users SET name = ?", "secret").Error; err != nil {
+		t.Fatalf("failed to exec, got %v", err)
+	}
+
+	if log := buf.String(); !strings.Contains(log, `"sql":"UPDATE users SET name = ?"`) || strings.Contains(log, "secret") {
+		t.Errorf("params should not be logged, got %v", log)
+	}
+}
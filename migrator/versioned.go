@@ -0,0 +1,250 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMigrationLocked migrations are being run by another runner
+	ErrMigrationLocked = errors.New("migrations are locked by another runner")
+	// ErrUnknownMigration migration id not found
+	ErrUnknownMigration = errors.New("unknown migration")
+	// ErrIrreversibleMigration rolling back migrations without Down
+	ErrIrreversibleMigration = errors.New("irreversible migration")
+	// ErrInvalidMigration migration without ID or Up, or with duplicated ID
+	ErrInvalidMigration = errors.New("invalid migration")
+)
+
+// Migration versioned migration, migrations are applied in the order they are passed to NewVersioned
+type Migration struct {
+	ID   string
+	Up   func(tx *gorm.DB) error
+	Down func(tx *gorm.DB) error
+	// DisableTransaction runs the migration outside of transaction, e.g. CREATE INDEX CONCURRENTLY of PostgreSQL
+	DisableTransaction bool
+}
+
+// SchemaMigration applied migration
+type SchemaMigration struct {
+	ID        string `gorm:"primaryKey;size:255"`
+	AppliedAt time.Time
+}
+
+// SchemaMigrationLock lock of migration runners, there is a record while migrations are running
+type SchemaMigrationLock struct {
+	ID       int `gorm:"primaryKey;autoIncrement:false"`
+	LockedAt time.Time
+	LockedBy string `gorm:"size:255"`
+}
+
+// VersionedConfig config of versioned migrations
+type VersionedConfig struct {
+	// TableName table of applied migrations, default to schema_migrations, the lock table has a _lock suffix
+	TableName string
+	// DisableTransaction runs migrations outside of transactions, migrations run in transactions by default
+	// except on MySQL, which commits DDL statements implicitly
+	DisableTransaction bool
+	// LockTimeout how long to wait for other runners, default to 0 which fails immediately
+	LockTimeout time.Duration
+}
+
+// Versioned versioned migration runner, applied migrations are tracked in a history table, and runners
+// are locked by a record in the lock table, so concurrent runners wait or fail with ErrMigrationLocked
+//
+//	m := migrator.NewVersioned(db, migrator.VersionedConfig{}, []*migrator.Migration{{
+//		ID: "202401020304_create_users",
+//		Up: func(tx *gorm.DB) error { return tx.Migrator().CreateTable(&User{}) },
+//		Down: func(tx *gorm.DB) error { return tx.Migrator().DropTable("users") },
+//	}})
+//	err := m.Migrate()
+type Versioned struct {
+	db         *gorm.DB
+	config     VersionedConfig
+	migrations []*Migration
+}
+
+// NewVersioned returns a versioned migration runner of migrations
+func NewVersioned(db *gorm.DB, config VersionedConfig, migrations []*Migration) *Versioned {
+	if config.TableName == "" {
+		config.TableName = "schema_migrations"
+	}
+	return &Versioned{db: db, config: config, migrations: migrations}
+}
+
+// Pending returns migrations not applied yet
+func (v *Versioned) Pending() (pending []*Migration, err error) {
+	err = v.run(false, func(applied map[string]bool) error {
+		for _, m := range v.migrations {
+			if !applied[m.ID] {
+				pending = append(pending, m)
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// Migrate applies all pending migrations
+func (v *Versioned) Migrate() error {
+	return v.migrate("")
+}
+
+// MigrateTo applies pending migrations until the migration of id, which is applied too, and rolls back applied
+// migrations after it in the reverse order
+func (v *Versioned) MigrateTo(id string) error {
+	if v.lookUp(id) < 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownMigration, id)
+	}
+	return v.migrate(id)
+}
+
+func (v *Versioned) migrate(target string) error {
+	return v.run(true, func(applied map[string]bool) error {
+		end := len(v.migrations) - 1
+		if target != "" {
+			end = v.lookUp(target)
+		}
+
+		for idx := len(v.migrations) - 1; idx > end; idx-- {
+			if m := v.migrations[idx]; applied[m.ID] {
+				if err := v.apply(m, true); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, m := range v.migrations[:end+1] {
+			if !applied[m.ID] {
+				if err := v.apply(m, false); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Rollback rolls back the last steps applied migrations in the reverse order
+func (v *Versioned) Rollback(steps int) error {
+	return v.run(true, func(applied map[string]bool) error {
+		for idx := len(v.migrations) - 1; idx >= 0 && steps > 0; idx-- {
+			if m := v.migrations[idx]; applied[m.ID] {
+				if err := v.apply(m, true); err != nil {
+					return err
+				}
+				steps--
+			}
+		}
+		return nil
+	})
+}
+
+// Unlock removes the lock left by a crashed runner
+func (v *Versioned) Unlock() error {
+	return v.db.Table(v.lockTableName()).Where("id = ?", 1).Delete(&SchemaMigrationLock{}).Error
+}
+
+func (v *Versioned) lockTableName() string {
+	return v.config.TableName + "_lock"
+}
+
+func (v *Versioned) lookUp(id string) int {
+	for idx, m := range v.migrations {
+		if m.ID == id {
+			return idx
+		}
+	}
+	return -1
+}
+
+// run validates migrations, creates the history and lock tables, and runs fc with applied migrations, while
+// locked if lock is true
+func (v *Versioned) run(lock bool, fc func(applied map[string]bool) error) (err error) {
+	ids := make(map[string]bool, len(v.migrations))
+	for _, m := range v.migrations {
+		if m == nil || m.ID == "" || m.Up == nil || ids[m.ID] {
+			return fmt.Errorf("%w: %+v", ErrInvalidMigration, m)
+		}
+		ids[m.ID] = true
+	}
+
+	if err = v.db.Table(v.config.TableName).AutoMigrate(&SchemaMigration{}); err != nil {
+		return err
+	}
+
+	if err = v.db.Table(v.lockTableName()).AutoMigrate(&SchemaMigrationLock{}); err != nil {
+		return err
+	}
+
+	if lock {
+		if err = v.lock(); err != nil {
+			return err
+		}
+
+		defer func() {
+			if unlockErr := v.Unlock(); err == nil {
+				err = unlockErr
+			}
+		}()
+	}
+
+	var records []SchemaMigration
+	if err = v.db.Table(v.config.TableName).Find(&records).Error; err != nil {
+		return err
+	}
+
+	applied := make(map[string]bool, len(records))
+	for _, record := range records {
+		applied[record.ID] = true
+	}
+	return fc(applied)
+}
+
+func (v *Versioned) lock() error {
+	hostname, _ := os.Hostname()
+	deadline := time.Now().Add(v.config.LockTimeout)
+
+	for {
+		lock := SchemaMigrationLock{ID: 1, LockedAt: v.db.NowFunc(), LockedBy: fmt.Sprintf("%s:%d", hostname, os.Getpid())}
+		err := v.db.Table(v.lockTableName()).Create(&lock).Error
+		if err == nil {
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%w: %v", ErrMigrationLocked, err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// apply applies or rolls back the migration and updates the history in a transaction if enabled
+func (v *Versioned) apply(m *Migration, rollback bool) error {
+	fc := func(tx *gorm.DB) error {
+		if rollback {
+			if m.Down == nil {
+				return fmt.Errorf("%w: %s", ErrIrreversibleMigration, m.ID)
+			}
+
+			if err := m.Down(tx); err != nil {
+				return fmt.Errorf("failed to roll back migration %s: %w", m.ID, err)
+			}
+			return tx.Table(v.config.TableName).Delete(&SchemaMigration{ID: m.ID}).Error
+		}
+
+		if err := m.Up(tx); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", m.ID, err)
+		}
+		return tx.Table(v.config.TableName).Create(&SchemaMigration{ID: m.ID, AppliedAt: tx.NowFunc()}).Error
+	}
+
+	if v.config.DisableTransaction || m.DisableTransaction || v.db.Dialector.Name() == "mysql" {
+		return fc(v.db.Session(&gorm.Session{NewDB: true}))
+	}
+	return v.db.Session(&gorm.Session{NewDB: true}).Transaction(fc)
+}
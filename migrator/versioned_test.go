@@ -0,0 +1,154 @@
+package migrator_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/utils/tests"
+)
+
+type memoryMigrator struct {
+	gorm.Migrator
+}
+
+func (memoryMigrator) AutoMigrate(...interface{}) error { return nil }
+
+type memoryDialector struct {
+	tests.DummyDialector
+}
+
+func (memoryDialector) Migrator(*gorm.DB) gorm.Migrator { return memoryMigrator{} }
+
+type beginnerPool struct {
+	gorm.ConnPool
+	commits, rollbacks *int
+}
+
+type txPool beginnerPool
+
+func (p beginnerPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	tx := txPool(p)
+	return &tx, nil
+}
+
+func (p *txPool) Commit() error   { *p.commits++; return nil }
+func (p *txPool) Rollback() error { *p.rollbacks++; return nil }
+
+func TestVersioned(t *testing.T) {
+	var (
+		commits, rollbacks int
+		db, _              = gorm.Open(memoryDialector{}, &gorm.Config{ConnPool: beginnerPool{commits: &commits, rollbacks: &rollbacks}, SkipDefaultTransaction: true})
+		history            []string
+		locked             bool
+		steps              []string
+	)
+
+	db.Callback().Create().Replace("gorm:create", func(db *gorm.DB) {
+		switch v := db.Statement.Dest.(type) {
+		case *migrator.SchemaMigration:
+			history = append(history, v.ID)
+		case *migrator.SchemaMigrationLock:
+			if locked {
+				db.AddError(errors.New("duplicated key"))
+				return
+			}
+			locked = true
+		}
+	})
+
+	db.Callback().Query().Replace("gorm:query", func(db *gorm.DB) {
+		if records, ok := db.Statement.Dest.(*[]migrator.SchemaMigration); ok {
+			for _, id := range history {
+				*records = append(*records, migrator.SchemaMigration{ID: id})
+			}
+		}
+	})
+
+	db.Callback().Delete().Replace("gorm:delete", func(db *gorm.DB) {
+		switch v := db.Statement.Dest.(type) {
+		case *migrator.SchemaMigration:
+			for idx, id := range history {
+				if id == v.ID {
+					history = append(history[:idx], history[idx+1:]...)
+				}
+			}
+		case *migrator.SchemaMigrationLock:
+			locked = false
+		}
+	})
+
+	migration := func(id string, reversible bool) *migrator.Migration {
+		m := &migrator.Migration{ID: id, Up: func(tx *gorm.DB) error {
+			if _, ok := tx.Statement.ConnPool.(gorm.TxCommitter); !ok {
+				t.Errorf("migration %v should be run in transaction", id)
+			}
+			steps = append(steps, "up "+id)
+			return nil
+		}}
+		if reversible {
+			m.Down = func(tx *gorm.DB) error {
+				steps = append(steps, "down "+id)
+				return nil
+			}
+		}
+		return m
+	}
+
+	m := migrator.NewVersioned(db, migrator.VersionedConfig{}, []*migrator.Migration{migration("1", false), migration("2", true), migration("3", true)})
+	if err := m.MigrateTo("2"); err != nil {
+		t.Fatalf("failed to migrate to 2, got %v", err)
+	}
+
+	if pending, err := m.Pending(); err != nil || len(pending) != 1 || pending[0].ID != "3" {
+		t.Errorf("migration 3 should be pending, got %+v, err %v", pending, err)
+	}
+
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("failed to migrate, got %v", err)
+	}
+
+	if err := m.MigrateTo("2"); err != nil {
+		t.Fatalf("failed to migrate back to 2, got %v", err)
+	}
+
+	if pending, err := m.Pending(); err != nil || len(pending) != 1 || pending[0].ID != "3" {
+		t.Errorf("migration 3 should be rolled back, got %+v, err %v", pending, err)
+	}
+
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("failed to migrate, got %v", err)
+	}
+
+	if err := m.Rollback(2); err != nil {
+		t.Fatalf("failed to roll back, got %v", err)
+	}
+
+	if err := m.Rollback(1); !errors.Is(err, migrator.ErrIrreversibleMigration) {
+		t.Errorf("rolling back migration without down should fail, got %v", err)
+	}
+
+	tests.AssertEqual(t, steps, []string{"up 1", "up 2", "up 3", "down 3", "up 3", "down 3", "down 2"})
+	tests.AssertEqual(t, history, []string{"1"})
+	tests.AssertEqual(t, commits, 7)
+	tests.AssertEqual(t, rollbacks, 1)
+
+	if locked {
+		t.Errorf("lock should be released")
+	}
+
+	locked = true
+	if err := m.Migrate(); !errors.Is(err, migrator.ErrMigrationLocked) {
+		t.Errorf("migrating while locked should fail, got %v", err)
+	}
+	if !locked {
+		t.Errorf("lock of other runner shouldn't be released")
+	}
+
+	if err := m.MigrateTo("4"); !errors.Is(err, migrator.ErrUnknownMigration) {
+		t.Errorf("migrating to unknown migration should fail, got %v", err)
+	}
+}
@@ -0,0 +1,360 @@
+// Package audit records the change history of auditable models, old and new values of changed columns
+// are written to a history table together with the actor, table, primary key and operation.
+//
+//	db.Use(audit.New(audit.Config{}))
+//	db.Table("audit_records").AutoMigrate(&audit.Record{})
+//
+//	func (User) AuditEnabled() bool { return true }
+//
+//	db.WithContext(audit.WithActor(ctx, "jinzhu")).Model(&user).Update("name", "hello")
+//
+// Old values are loaded before updates and deletes, and updated records are reloaded afterwards, so values
+// computed by the database like gorm.Expr("age + 1") are recorded as they are stored. History records are
+// written with the connection of the operation, which is its default transaction, they are not atomic with
+// the change when SkipDefaultTransaction is set unless the operation runs in a transaction.
+//
+// Values of serializer fields are recorded as their Go values, except values of encrypted fields, which are
+// recorded as Redacted.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// operations of history records
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+const oldRecordsKey = "audit:old_records"
+
+// Redacted recorded value of encrypted fields
+const Redacted = "[redacted]"
+
+// Auditable models implementing it and returning true are audited
+type Auditable interface {
+	AuditEnabled() bool
+}
+
+// Record history record of a created, updated or deleted record
+type Record struct {
+	ID         uint64 `gorm:"primaryKey"`
+	Table      string `gorm:"column:table_name;size:64;index:idx_audit_records_table_key"`
+	PrimaryKey string `gorm:"size:191;index:idx_audit_records_table_key"`
+	Operation  string `gorm:"size:16"`
+	Actor      string `gorm:"size:191"`
+	// Changes JSON object of changed columns to their Change
+	Changes   string
+	CreatedAt time.Time
+}
+
+// Change old and new values of a column, Old is nil for created records and New is nil for deleted records
+type Change struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Config audit plugin config
+type Config struct {
+	// Table history table, default to audit_records
+	Table string
+	// Actor returns the actor of changes, default to the actor set with WithActor
+	Actor func(context.Context) string
+}
+
+// Audit change history plugin
+type Audit struct {
+	Config
+}
+
+type actorKey struct{}
+
+// WithActor returns a context recording changes made with it by actor
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set with WithActor
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// New returns an audit plugin
+func New(config Config) *Audit {
+	if config.Table == "" {
+		config.Table = "audit_records"
+	}
+	if config.Actor == nil {
+		config.Actor = ActorFromContext
+	}
+	return &Audit{Config: config}
+}
+
+func (a *Audit) Name() string {
+	return "gorm:audit"
+}
+
+func (a *Audit) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().After("gorm:create").Register("audit:after_create", a.afterCreate); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Update().After("gorm:begin_transaction").Before("gorm:update").Register("audit:before_update", a.loadOldRecords); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Update().After("gorm:update").Register("audit:after_update", a.afterUpdate); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Delete().After("gorm:begin_transaction").Before("gorm:delete").Register("audit:before_delete", a.loadOldRecords); err != nil {
+		return err
+	}
+
+	return db.Callback().Delete().After("gorm:delete").Register("audit:after_delete", a.afterDelete)
+}
+
+func (a *Audit) afterCreate(db *gorm.DB) {
+	if !auditable(db) || db.RowsAffected == 0 {
+		return
+	}
+
+	var records []*Record
+	eachRecord(db.Statement.ReflectValue, func(rv reflect.Value) {
+		changes := map[string]Change{}
+		for name, value := range columnValues(db.Statement, rv) {
+			changes[name] = Change{New: value}
+		}
+		records = append(records, a.newRecord(db, OpCreate, rv, changes))
+	})
+	a.write(db, records)
+}
+
+// loadOldRecords loads records matching the update or delete before it's executed
+func (a *Audit) loadOldRecords(db *gorm.DB) {
+	if !auditable(db) {
+		return
+	}
+
+	stmt := db.Statement
+	var conds []clause.Expression
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			conds = append(conds, where)
+		}
+	}
+
+	if _, primaryValues := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields); len(primaryValues) > 0 {
+		column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, primaryValues)
+		conds = append(conds, clause.IN{Column: column, Values: values})
+	}
+
+	// the operation fails with gorm.ErrMissingWhereClause
+	if len(conds) == 0 && !stmt.AllowGlobalUpdate {
+		return
+	}
+
+	records, err := find(db, stmt.Unscoped, conds)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.InstanceSet(oldRecordsKey, records)
+}
+
+func (a *Audit) afterUpdate(db *gorm.DB) {
+	olds, ok := oldRecords(db)
+	if !ok {
+		return
+	}
+
+	stmt := db.Statement
+	_, primaryValues := schema.GetIdentityFieldValuesMap(stmt.Context, olds, stmt.Schema.PrimaryFields)
+	if len(primaryValues) == 0 {
+		return
+	}
+
+	column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, primaryValues)
+	news, err := find(db, true, []clause.Expression{clause.IN{Column: column, Values: values}})
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+
+	updated := map[string]reflect.Value{}
+	eachRecord(news, func(rv reflect.Value) {
+		updated[primaryKey(stmt, rv)] = rv
+	})
+
+	var records []*Record
+	eachRecord(olds, func(rv reflect.Value) {
+		newRV, ok := updated[primaryKey(stmt, rv)]
+		if !ok {
+			return
+		}
+
+		changes := map[string]Change{}
+		newValues := columnValues(stmt, newRV)
+		for name, value := range columnValues(stmt, rv) {
+			if !reflect.DeepEqual(value, newValues[name]) {
+				changes[name] = Change{Old: value, New: newValues[name]}
+			}
+		}
+
+		if len(changes) > 0 {
+			records = append(records, a.newRecord(db, OpUpdate, rv, changes))
+		}
+	})
+	a.write(db, records)
+}
+
+func (a *Audit) afterDelete(db *gorm.DB) {
+	olds, ok := oldRecords(db)
+	if !ok {
+		return
+	}
+
+	var records []*Record
+	eachRecord(olds, func(rv reflect.Value) {
+		changes := map[string]Change{}
+		for name, value := range columnValues(db.Statement, rv) {
+			changes[name] = Change{Old: value}
+		}
+		records = append(records, a.newRecord(db, OpDelete, rv, changes))
+	})
+	a.write(db, records)
+}
+
+func (a *Audit) newRecord(db *gorm.DB, operation string, rv reflect.Value, changes map[string]Change) *Record {
+	for name, change := range changes {
+		if _, ok := db.Statement.Schema.FieldsByDBName[name].Serializer.(schema.EncryptedSerializer); ok {
+			changes[name] = Change{Old: redact(change.Old), New: redact(change.New)}
+		}
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		db.AddError(fmt.Errorf("failed to marshal audit changes: %w", err))
+	}
+
+	return &Record{
+		Table:      db.Statement.Table,
+		PrimaryKey: primaryKey(db.Statement, rv),
+		Operation:  operation,
+		Actor:      a.Actor(db.Statement.Context),
+		Changes:    string(data),
+	}
+}
+
+// write creates history records with the connection of the operation, which is its transaction if started
+func (a *Audit) write(db *gorm.DB, records []*Record) {
+	if len(records) == 0 || db.Error != nil {
+		return
+	}
+
+	tx := db.Session(&gorm.Session{NewDB: true, SkipHooks: true})
+	if err := tx.Table(a.Table).Create(&records).Error; err != nil {
+		db.AddError(err)
+	}
+}
+
+// RequiresCreateCallbacks implements gorm.BulkLoadPlugin, rows of auditable models are loaded by create callbacks,
+// which record their history
+func (a *Audit) RequiresCreateCallbacks(stmt *gorm.Statement) bool {
+	return auditable(stmt.DB)
+}
+
+func auditable(db *gorm.DB) bool {
+	if db.Error != nil || db.DryRun || db.Statement.Schema == nil {
+		return false
+	}
+
+	model, ok := reflect.New(db.Statement.Schema.ModelType).Interface().(Auditable)
+	return ok && model.AuditEnabled()
+}
+
+func oldRecords(db *gorm.DB) (reflect.Value, bool) {
+	if db.Error != nil || db.RowsAffected == 0 {
+		return reflect.Value{}, false
+	}
+
+	v, ok := db.InstanceGet(oldRecordsKey)
+	if !ok {
+		return reflect.Value{}, false
+	}
+
+	records := v.(reflect.Value)
+	return records, records.Len() > 0
+}
+
+// find finds records of the statement's model matching conds
+func find(db *gorm.DB, unscoped bool, conds []clause.Expression) (reflect.Value, error) {
+	stmt := db.Statement
+	records := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+
+	tx := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Table(stmt.Table)
+	if unscoped {
+		tx = tx.Unscoped()
+	}
+
+	err := tx.Clauses(conds...).Find(records.Interface()).Error
+	return records.Elem(), err
+}
+
+func eachRecord(rv reflect.Value, fc func(reflect.Value)) {
+	rv = reflect.Indirect(rv)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if elem := reflect.Indirect(rv.Index(i)); elem.Kind() == reflect.Struct {
+				fc(elem)
+			}
+		}
+	case reflect.Struct:
+		fc(rv)
+	}
+}
+
+// columnValues values of readable columns of the record, values of serializer fields are their Go values
+func columnValues(stmt *gorm.Statement, rv reflect.Value) map[string]interface{} {
+	values := make(map[string]interface{}, len(stmt.Schema.DBNames))
+	for _, name := range stmt.Schema.DBNames {
+		if field := stmt.Schema.FieldsByDBName[name]; !field.Readable {
+			continue
+		} else if field.Serializer != nil {
+			values[name] = field.ReflectValueOf(stmt.Context, rv).Interface()
+		} else {
+			values[name], _ = field.ValueOf(stmt.Context, rv)
+		}
+	}
+	return values
+}
+
+func redact(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return Redacted
+}
+
+// primaryKey primary key of the record, values of composite primary keys are joined by comma
+func primaryKey(stmt *gorm.Statement, rv reflect.Value) string {
+	values := make([]string, 0, len(stmt.Schema.PrimaryFields))
+	for _, field := range stmt.Schema.PrimaryFields {
+		value, _ := field.ValueOf(stmt.Context, rv)
+		values = append(values, fmt.Sprint(value))
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,164 @@
+package audit_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/plugin/audit"
+	"gorm.io/gorm/utils/tests"
+)
+
+type auditUser struct {
+	ID   uint
+	Name string
+	Age  int
+}
+
+func (auditUser) AuditEnabled() bool { return true }
+
+type plainUser struct {
+	ID   uint
+	Name string
+}
+
+func TestAudit(t *testing.T) {
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{SkipDefaultTransaction: true})
+	if err := db.Use(audit.New(audit.Config{})); err != nil {
+		t.Fatalf("failed to use audit plugin, got %v", err)
+	}
+
+	var (
+		stored  = map[uint]auditUser{}
+		history []audit.Record
+	)
+
+	db.Callback().Create().Replace("gorm:create", func(db *gorm.DB) {
+		switch dest := db.Statement.Dest.(type) {
+		case *[]*audit.Record:
+			if db.Statement.Table != "audit_records" {
+				t.Errorf("history should be written to audit_records, got %v", db.Statement.Table)
+			}
+			for _, record := range *dest {
+				history = append(history, *record)
+			}
+		case *auditUser:
+			dest.ID = uint(len(stored) + 1)
+			stored[dest.ID] = *dest
+		}
+		db.RowsAffected = 1
+	})
+
+	db.Callback().Query().Replace("gorm:query", func(db *gorm.DB) {
+		if dest, ok := db.Statement.Dest.(*[]auditUser); ok {
+			for _, user := range stored {
+				*dest = append(*dest, user)
+			}
+			db.RowsAffected = int64(len(*dest))
+		}
+	})
+
+	db.Callback().Update().Replace("gorm:update", func(db *gorm.DB) {
+		user := stored[1]
+		user.Name = "hello"
+		stored[1] = user
+		db.RowsAffected = 1
+	})
+
+	db.Callback().Delete().Replace("gorm:delete", func(db *gorm.DB) {
+		delete(stored, 1)
+		db.RowsAffected = 1
+	})
+
+	tx := db.WithContext(audit.WithActor(context.Background(), "admin"))
+
+	user := auditUser{Name: "jinzhu", Age: 18}
+	tx.Create(&user)
+	tx.Model(&user).Update("name", "hello")
+	tx.Delete(&user)
+	tx.Create(&plainUser{Name: "plain"})
+
+	expects := []audit.Record{
+		{Table: "audit_users", PrimaryKey: "1", Operation: audit.OpCreate, Actor: "admin", Changes: `{"age":{"old":null,"new":18},"id":{"old":null,"new":1},"name":{"old":null,"new":"jinzhu"}}`},
+		{Table: "audit_users", PrimaryKey: "1", Operation: audit.OpUpdate, Actor: "admin", Changes: `{"name":{"old":"jinzhu","new":"hello"}}`},
+		{Table: "audit_users", PrimaryKey: "1", Operation: audit.OpDelete, Actor: "admin", Changes: `{"age":{"old":18,"new":null},"id":{"old":1,"new":null},"name":{"old":"hello","new":null}}`},
+	}
+
+	if len(history) != len(expects) {
+		t.Fatalf("expects %v history records, got %+v", len(expects), history)
+	}
+
+	for idx, expect := range expects {
+		record := history[idx]
+		record.CreatedAt = expect.CreatedAt
+		if !reflect.DeepEqual(record, expect) {
+			t.Errorf("#%v expects history record %+v, got %+v", idx, expect, record)
+		}
+	}
+}
+
+type auditAccount struct {
+	ID       uint
+	Settings map[string]string `gorm:"serializer:json"`
+	Secret   string            `gorm:"serializer:encrypted"`
+}
+
+func (auditAccount) AuditEnabled() bool { return true }
+
+func TestAuditSerializer(t *testing.T) {
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{SkipDefaultTransaction: true})
+	if err := db.Use(audit.New(audit.Config{})); err != nil {
+		t.Fatalf("failed to use audit plugin, got %v", err)
+	}
+
+	var history []audit.Record
+	db.Callback().Create().Replace("gorm:create", func(db *gorm.DB) {
+		if dest, ok := db.Statement.Dest.(*[]*audit.Record); ok {
+			for _, record := range *dest {
+				history = append(history, *record)
+			}
+		}
+		db.RowsAffected = 1
+	})
+
+	if err := db.Create(&auditAccount{ID: 1, Settings: map[string]string{"theme": "dark"}, Secret: "s3cret"}).Error; err != nil {
+		t.Fatalf("failed to create account, got %v", err)
+	}
+
+	if len(history) != 1 {
+		t.Fatalf("expects 1 history record, got %+v", history)
+	}
+	tests.AssertEqual(t, history[0].Changes, `{"id":{"old":null,"new":1},"secret":{"old":null,"new":"[redacted]"},"settings":{"old":null,"new":{"theme":"dark"}}}`)
+}
+
+func TestAuditBulkLoad(t *testing.T) {
+	dialector := &tests.BulkLoadDialector{}
+	db, _ := gorm.Open(dialector, &gorm.Config{SkipDefaultTransaction: true})
+	if err := db.Use(audit.New(audit.Config{})); err != nil {
+		t.Fatalf("failed to use audit plugin, got %v", err)
+	}
+
+	var history []audit.Record
+	db.Callback().Create().Replace("gorm:create", func(db *gorm.DB) {
+		if dest, ok := db.Statement.Dest.(*[]*audit.Record); ok {
+			for _, record := range *dest {
+				history = append(history, *record)
+			}
+		}
+		db.RowsAffected = 1
+	})
+
+	if err := db.BulkLoad("", &[]auditUser{{ID: 1, Name: "jinzhu"}}).Error; err != nil {
+		t.Fatalf("failed to bulk load users, got %v", err)
+	}
+
+	if err := db.BulkLoad("", &[]plainUser{{Name: "plain"}}).Error; err != nil {
+		t.Fatalf("failed to bulk load plain users, got %v", err)
+	}
+
+	if len(history) != 1 || history[0].Operation != audit.OpCreate || history[0].PrimaryKey != "1" {
+		t.Errorf("bulk loaded users should be audited, got %+v", history)
+	}
+	tests.AssertEqual(t, dialector.Columns["plain_users"], []string{"name"})
+}
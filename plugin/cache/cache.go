@@ -0,0 +1,337 @@
+// Package cache caches query results of GORM in a pluggable store, cached entries are invalidated
+// by table when records of the table are created, updated or deleted through GORM.
+//
+//	db.Use(cache.New(cache.Config{Store: cache.NewLRUStore(10000)}))
+//
+//	// cache results for a minute
+//	db.Set(cache.SettingKey, time.Minute).Find(&users)
+//	db.Scopes(cache.TTL(time.Minute)).Find(&users)
+//
+// Only Find, First, Take, Last, Count and Pluck are served from the cache, Row, Rows and Scan return
+// database cursors and always hit the database. Queries in transactions or with locking clauses are not cached,
+// and writes by Exec or outside of the application won't invalidate cached entries, so pick a proper TTL.
+// Entries are invalidated once the write is committed, writes in transactions started with Transaction or Begin
+// invalidate entries when they are executed and again after the transaction is committed, as the plugin wraps the
+// connection pool of the db to track its transactions.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/logger"
+)
+
+// SettingKey statement setting key of cache TTL, a zero or negative TTL disables caching for the query
+const SettingKey = "gorm:cache"
+
+// Config cache plugin config
+type Config struct {
+	// Store default to an LRUStore of 1000 entries
+	Store Store
+	// TTL default TTL of queries, queries are only cached if a TTL is set with SettingKey when it's zero
+	TTL time.Duration
+}
+
+// Cache query cache plugin
+type Cache struct {
+	Config
+	query func(*gorm.DB)
+}
+
+func init() {
+	gob.Register(time.Time{})
+}
+
+// New returns a query cache plugin
+func New(config Config) *Cache {
+	if config.Store == nil {
+		config.Store = NewLRUStore(0)
+	}
+	return &Cache{Config: config}
+}
+
+// TTL scope that caches query results for ttl
+func TTL(ttl time.Duration) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Set(SettingKey, ttl)
+	}
+}
+
+func (c *Cache) Name() string {
+	return "gorm:cache"
+}
+
+func (c *Cache) Initialize(db *gorm.DB) error {
+	c.query = db.Callback().Query().Get("gorm:query")
+	if c.query == nil {
+		return fmt.Errorf("gorm:query callback not found")
+	}
+
+	if err := db.Callback().Query().Replace("gorm:query", c.queryCallback); err != nil {
+		return err
+	}
+
+	// track tables written in transactions, which are invalidated after the transaction is committed
+	switch pool := db.ConnPool.(type) {
+	case nil:
+	case *gorm.PreparedStmtDB:
+		pool.ConnPool = &connPool{ConnPool: pool.ConnPool, cache: c, logger: db.Logger}
+	default:
+		db.ConnPool = &connPool{ConnPool: pool, cache: c, logger: db.Logger}
+		db.Statement.ConnPool = db.ConnPool
+	}
+
+	// invalidate after the default transaction is committed, otherwise concurrent queries might cache the
+	// records before the change is visible
+	if err := db.Callback().Create().After("gorm:commit_or_rollback_transaction").Register("cache:invalidate", c.invalidate); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Update().After("gorm:commit_or_rollback_transaction").Register("cache:invalidate", c.invalidate); err != nil {
+		return err
+	}
+
+	return db.Callback().Delete().After("gorm:commit_or_rollback_transaction").Register("cache:invalidate", c.invalidate)
+}
+
+func (c *Cache) queryCallback(db *gorm.DB) {
+	ttl := c.ttl(db)
+	if ttl <= 0 || db.Error != nil || db.DryRun {
+		c.query(db)
+		return
+	}
+
+	tables := queryTables(db.Statement)
+	if len(tables) == 0 || reflect.ValueOf(db.Statement.Dest).Kind() != reflect.Ptr {
+		c.query(db)
+		return
+	}
+
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		c.query(db)
+		return
+	}
+
+	// build SQL before looking up the cache, gorm:query won't build it again
+	callbacks.BuildQuerySQL(db)
+	if db.Error != nil {
+		return
+	}
+
+	if _, ok := db.Statement.Clauses["FOR"]; ok {
+		c.query(db)
+		return
+	}
+
+	ctx := db.Statement.Context
+	key := cacheKey(db.Statement)
+	if data, ok, err := c.Store.Get(ctx, key); err != nil {
+		db.Logger.Warn(ctx, "failed to get query cache: %v", err)
+	} else if ok {
+		// decoded into a new value, so dest isn't left with stale or partially decoded values
+		var (
+			rowsAffected int64
+			dest         = reflect.New(reflect.TypeOf(db.Statement.Dest).Elem())
+			decoder      = gob.NewDecoder(bytes.NewReader(data))
+		)
+
+		if err := decoder.Decode(&rowsAffected); err == nil && decoder.DecodeValue(dest) == nil {
+			reflect.ValueOf(db.Statement.Dest).Elem().Set(dest.Elem())
+			db.RowsAffected = rowsAffected
+			if db.RowsAffected == 0 && db.Statement.RaiseErrorOnNotFound {
+				db.AddError(gorm.ErrRecordNotFound)
+			}
+			return
+		}
+	}
+
+	c.query(db)
+	if db.Error != nil {
+		return
+	}
+
+	// cached as rows affected followed by dest, dest is decoded into the dest of later queries
+	var (
+		buf     bytes.Buffer
+		encoder = gob.NewEncoder(&buf)
+	)
+
+	err := encoder.Encode(db.RowsAffected)
+	if err == nil {
+		err = encoder.Encode(db.Statement.Dest)
+	}
+
+	if err != nil {
+		db.Logger.Warn(ctx, "failed to encode query cache: %v", err)
+		return
+	}
+
+	if err := c.Store.Set(ctx, key, buf.Bytes(), ttl, tables); err != nil {
+		db.Logger.Warn(ctx, "failed to set query cache: %v", err)
+	}
+}
+
+func (c *Cache) invalidate(db *gorm.DB) {
+	if db.Error != nil || db.DryRun || db.Statement.Table == "" {
+		return
+	}
+
+	if tx, ok := transactionOf(db.Statement.ConnPool); ok {
+		tx.track(db.Statement.Table)
+	}
+
+	if err := c.Store.Invalidate(db.Statement.Context, db.Statement.Table); err != nil {
+		db.Logger.Warn(db.Statement.Context, "failed to invalidate query cache: %v", err)
+	}
+}
+
+func (c *Cache) ttl(db *gorm.DB) time.Duration {
+	if v, ok := db.Get(SettingKey); ok {
+		if ttl, ok := v.(time.Duration); ok {
+			return ttl
+		}
+		return 0
+	}
+	return c.TTL
+}
+
+// queryTables tables read by the query, including tables of joined associations
+func queryTables(stmt *gorm.Statement) []string {
+	if stmt.Table == "" {
+		return nil
+	}
+
+	tables := []string{stmt.Table}
+	if stmt.Schema != nil {
+		for _, join := range stmt.Joins {
+			if rel, ok := stmt.Schema.Relationships.Relations[join.Name]; ok {
+				tables = append(tables, rel.FieldSchema.Table)
+			} else {
+				// raw joins might read any table
+				return nil
+			}
+		}
+	}
+	return tables
+}
+
+func cacheKey(stmt *gorm.Statement) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%T\x00%s", stmt.Dest, stmt.SQL.String())
+	for _, v := range stmt.Vars {
+		fmt.Fprintf(h, "\x00%T:%v", v, v)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// connPool connection pool of the db, transactions begun with it invalidate written tables after committed
+type connPool struct {
+	gorm.ConnPool
+	cache  *Cache
+	logger logger.Interface
+}
+
+func (p *connPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	var tx gorm.ConnPool
+	switch beginner := p.ConnPool.(type) {
+	case gorm.TxBeginner:
+		sqlTx, err := beginner.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		tx = sqlTx
+	case gorm.ConnPoolBeginner:
+		connPool, err := beginner.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		tx = connPool
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+
+	committer, ok := tx.(gorm.TxCommitter)
+	if !ok {
+		return tx, nil
+	}
+	return &transaction{ConnPool: tx, committer: committer, ctx: ctx, pool: p}, nil
+}
+
+func (p *connPool) GetDBConn() (*sql.DB, error) {
+	if sqlDB, ok := p.ConnPool.(*sql.DB); ok {
+		return sqlDB, nil
+	}
+
+	if connector, ok := p.ConnPool.(gorm.GetDBConnector); ok {
+		return connector.GetDBConn()
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+// transaction transaction begun by connPool, which tracks tables written in it
+type transaction struct {
+	gorm.ConnPool
+	committer gorm.TxCommitter
+	ctx       context.Context
+	pool      *connPool
+
+	mu     sync.Mutex
+	tables []string
+}
+
+func transactionOf(pool gorm.ConnPool) (*transaction, bool) {
+	if preparedStmtTx, ok := pool.(*gorm.PreparedStmtTX); ok {
+		pool = preparedStmtTx.Tx
+	}
+
+	tx, ok := pool.(*transaction)
+	return tx, ok
+}
+
+func (tx *transaction) track(table string) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.tables = append(tx.tables, table)
+}
+
+func (tx *transaction) Commit() error {
+	if err := tx.committer.Commit(); err != nil {
+		return err
+	}
+
+	tx.mu.Lock()
+	tables := tx.tables
+	tx.tables = nil
+	tx.mu.Unlock()
+
+	if len(tables) > 0 {
+		if err := tx.pool.cache.Store.Invalidate(tx.ctx, tables...); err != nil {
+			tx.pool.logger.Warn(tx.ctx, "failed to invalidate query cache: %v", err)
+		}
+	}
+	return nil
+}
+
+func (tx *transaction) Rollback() error {
+	return tx.committer.Rollback()
+}
+
+func (tx *transaction) StmtContext(ctx context.Context, stmt *sql.Stmt) *sql.Stmt {
+	if stmtTx, ok := tx.ConnPool.(interface {
+		StmtContext(context.Context, *sql.Stmt) *sql.Stmt
+	}); ok {
+		return stmtTx.StmtContext(ctx, stmt)
+	}
+	return stmt
+}
@@ -0,0 +1,200 @@
+package cache_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/plugin/cache"
+	"gorm.io/gorm/utils/tests"
+)
+
+func TestCache(t *testing.T) {
+	db, _ := gorm.Open(tests.DummyDialector{}, nil)
+
+	// stub database access, queries return the user named by the current counter
+	var queries int
+	db.Callback().Query().Replace("gorm:query", func(db *gorm.DB) {
+		queries++
+		if users, ok := db.Statement.Dest.(*[]tests.User); ok {
+			*users = []tests.User{{Name: "jinzhu", Age: uint(queries)}}
+			db.RowsAffected = 1
+		}
+	})
+	db.Callback().Create().Replace("gorm:create", func(db *gorm.DB) {})
+
+	store := cache.NewLRUStore(10)
+	if err := db.Use(cache.New(cache.Config{Store: store})); err != nil {
+		t.Fatalf("failed to register cache plugin, got %v", err)
+	}
+
+	find := func(tx *gorm.DB) []tests.User {
+		var users []tests.User
+		if err := tx.Where("name = ?", "jinzhu").Find(&users).Error; err != nil {
+			t.Fatalf("failed to find users, got %v", err)
+		}
+		return users
+	}
+
+	find(db)
+	find(db)
+	if queries != 2 || store.Len() != 0 {
+		t.Fatalf("queries without TTL should not be cached, got %v queries, %v entries", queries, store.Len())
+	}
+
+	if users := find(db.Scopes(cache.TTL(time.Minute))); queries != 3 || users[0].Age != 3 {
+		t.Fatalf("first query should hit the database, got %v queries, %+v", queries, users)
+	}
+
+	if users := find(db.Set(cache.SettingKey, time.Minute)); queries != 3 || len(users) != 1 || users[0].Age != 3 {
+		t.Fatalf("second query should be served from cache, got %v queries, %+v", queries, users)
+	}
+
+	db.Create(&tests.Pet{Name: "pet"})
+	if find(db.Scopes(cache.TTL(time.Minute))); queries != 3 {
+		t.Fatalf("writes to other tables should not invalidate cache, got %v queries", queries)
+	}
+
+	db.Create(&tests.User{Name: "jinzhu"})
+	if users := find(db.Scopes(cache.TTL(time.Minute))); queries != 4 || users[0].Age != 4 {
+		t.Fatalf("writes to users should invalidate cache, got %v queries, %+v", queries, users)
+	}
+
+	users := []tests.User{{Name: "stale", Active: true}, {Name: "stale"}}
+	if err := db.Scopes(cache.TTL(time.Minute)).Where("name = ?", "jinzhu").Find(&users).Error; err != nil || queries != 4 {
+		t.Fatalf("query should be served from cache, got %v queries, error %v", queries, err)
+	}
+	if len(users) != 1 || users[0].Name != "jinzhu" || users[0].Age != 4 || users[0].Active {
+		t.Fatalf("cached results should replace values of dest, got %+v", users)
+	}
+
+	db.Callback().Create().Before("gorm:create").Register("test:fail", func(db *gorm.DB) {
+		if user, ok := db.Statement.Dest.(*tests.User); ok && user.Name == "fail" {
+			db.AddError(errors.New("failed"))
+		}
+	})
+	db.Create(&tests.User{Name: "fail"})
+	if find(db.Scopes(cache.TTL(time.Minute))); queries != 4 {
+		t.Fatalf("failed writes should not invalidate cache, got %v queries", queries)
+	}
+}
+
+type recordStore struct {
+	cache.Store
+	events *[]string
+}
+
+func (s recordStore) Invalidate(ctx context.Context, tables ...string) error {
+	*s.events = append(*s.events, "invalidate")
+	return s.Store.Invalidate(ctx, tables...)
+}
+
+func TestCacheInvalidateAfterCommit(t *testing.T) {
+	db, _ := gorm.Open(tests.DummyDialector{}, nil)
+
+	var events []string
+	db.Callback().Create().Replace("gorm:begin_transaction", func(db *gorm.DB) { events = append(events, "begin") })
+	db.Callback().Create().Replace("gorm:create", func(db *gorm.DB) { events = append(events, "create") })
+	db.Callback().Create().Replace("gorm:commit_or_rollback_transaction", func(db *gorm.DB) { events = append(events, "commit") })
+
+	if err := db.Use(cache.New(cache.Config{Store: recordStore{Store: cache.NewLRUStore(10), events: &events}})); err != nil {
+		t.Fatalf("failed to register cache plugin, got %v", err)
+	}
+
+	db.Create(&tests.User{Name: "jinzhu"})
+	tests.AssertEqual(t, events, []string{"begin", "create", "commit", "invalidate"})
+}
+
+type beginnerPool struct {
+	gorm.ConnPool
+}
+
+func (beginnerPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return &txPool{}, nil
+}
+
+type txPool struct {
+	gorm.ConnPool
+}
+
+func (*txPool) Commit() error   { return nil }
+func (*txPool) Rollback() error { return nil }
+
+func TestCacheInvalidateTransaction(t *testing.T) {
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{ConnPool: beginnerPool{}})
+
+	var queries int
+	db.Callback().Query().Replace("gorm:query", func(db *gorm.DB) {
+		queries++
+		if users, ok := db.Statement.Dest.(*[]tests.User); ok {
+			*users = []tests.User{{Name: "jinzhu", Age: uint(queries)}}
+			db.RowsAffected = 1
+		}
+	})
+	db.Callback().Create().Replace("gorm:create", func(db *gorm.DB) {})
+
+	if err := db.Use(cache.New(cache.Config{Store: cache.NewLRUStore(10), TTL: time.Minute})); err != nil {
+		t.Fatalf("failed to register cache plugin, got %v", err)
+	}
+
+	find := func() (users []tests.User) {
+		db.Find(&users)
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tests.User{Name: "jinzhu"}).Error; err != nil {
+			return err
+		}
+
+		// concurrent queries cache rows before the transaction is committed
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			find()
+		}()
+		wg.Wait()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to commit transaction, got %v", err)
+	}
+
+	if users := find(); queries != 2 || users[0].Age != 2 {
+		t.Fatalf("committed writes should invalidate cache, got %v queries, %+v", queries, users)
+	}
+
+	if sqlDB, err := db.DB(); err == nil || sqlDB != nil {
+		t.Errorf("db of pools without *sql.DB should fail, got %v", err)
+	}
+}
+
+func TestLRUStore(t *testing.T) {
+	ctx := context.Background()
+	store := cache.NewLRUStore(2)
+
+	store.Set(ctx, "a", []byte("a"), time.Minute, []string{"users"})
+	store.Set(ctx, "b", []byte("b"), time.Minute, []string{"pets"})
+	store.Get(ctx, "a")
+	store.Set(ctx, "c", []byte("c"), time.Minute, []string{"users", "pets"})
+
+	if _, ok, _ := store.Get(ctx, "b"); ok {
+		t.Errorf("least recently used entry should be evicted")
+	}
+
+	store.Set(ctx, "d", []byte("d"), -time.Second, nil)
+	if _, ok, _ := store.Get(ctx, "d"); ok {
+		t.Errorf("expired entry should not be returned")
+	}
+
+	store.Set(ctx, "b", []byte("b"), time.Minute, []string{"pets"})
+	store.Invalidate(ctx, "users")
+	if _, ok, _ := store.Get(ctx, "b"); !ok || store.Len() != 1 {
+		t.Errorf("only entries of invalidated tables should be removed, got %v entries", store.Len())
+	}
+}
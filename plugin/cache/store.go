@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Store cache store of query results, entries are tagged with the tables they are read from
+// so they could be invalidated when those tables are written
+type Store interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tables []string) error
+	Invalidate(ctx context.Context, tables ...string) error
+}
+
+// LRUStore in-memory store that evicts the least recently used entries when exceeding its capacity
+type LRUStore struct {
+	capacity int
+	mu       sync.Mutex
+	items    map[string]*list.Element
+	tables   map[string]map[string]struct{}
+	order    *list.List
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+	tables    []string
+}
+
+// NewLRUStore returns an in-memory LRU store holding at most capacity entries
+func NewLRUStore(capacity int) *LRUStore {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+
+	return &LRUStore{
+		capacity: capacity,
+		items:    map[string]*list.Element{},
+		tables:   map[string]map[string]struct{}{},
+		order:    list.New(),
+	}
+}
+
+func (s *LRUStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := elem.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		s.remove(elem)
+		return nil, false, nil
+	}
+
+	s.order.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+func (s *LRUStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tables []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		s.remove(elem)
+	}
+
+	entry := &lruEntry{key: key, value: value, expiresAt: time.Now().Add(ttl), tables: tables}
+	s.items[key] = s.order.PushFront(entry)
+	for _, table := range tables {
+		if s.tables[table] == nil {
+			s.tables[table] = map[string]struct{}{}
+		}
+		s.tables[table][key] = struct{}{}
+	}
+
+	for s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+func (s *LRUStore) Invalidate(ctx context.Context, tables ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, table := range tables {
+		for key := range s.tables[table] {
+			if elem, ok := s.items[key]; ok {
+				s.remove(elem)
+			}
+		}
+	}
+	return nil
+}
+
+// Len returns the number of cached entries, including expired ones that haven't been evicted yet
+func (s *LRUStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+func (s *LRUStore) remove(elem *list.Element) {
+	entry := s.order.Remove(elem).(*lruEntry)
+	delete(s.items, entry.key)
+	for _, table := range entry.tables {
+		if keys, ok := s.tables[table]; ok {
+			delete(keys, entry.key)
+			if len(keys) == 0 {
+				delete(s.tables, table)
+			}
+		}
+	}
+}
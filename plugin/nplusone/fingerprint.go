@@ -0,0 +1,131 @@
+package nplusone
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// lists of placeholders, e.g. `IN (?,?,?)`
+	placeholderListRe = regexp.MustCompile(`\?(?:\s*,\s*\?)+`)
+	// repeated rows of placeholders, e.g. `VALUES (?,?),(?,?)`
+	placeholderRowsRe = regexp.MustCompile(`\((?:\?|\?\+)\)(?:\s*,\s*\((?:\?|\?\+)\))+`)
+	// conditions of association loading, e.g. `user_id = ?`, `users.id IN (?+)`, `deleted_at IS NULL`
+	keyConditionRe  = regexp.MustCompile("^\\(*(?:[\\w.`\"]*[._`\"])?id[`\"]? (?:= \\?|in \\(\\?\\+?\\))\\)*$")
+	nullConditionRe = regexp.MustCompile("^\\(*[\\w.`\"]+ is null\\)*$")
+)
+
+// Fingerprint normalizes sql to a fingerprint, literals and placeholders are replaced with `?`, lists of placeholders
+// with `?+`, repeated rows of placeholders with `(?+)+`, and whitespaces are collapsed, so statements only differing
+// in vars have the same fingerprint
+//
+//	Fingerprint("SELECT * FROM users WHERE id IN ($1,$2) AND name = 'jinzhu'") // SELECT * FROM users WHERE id IN (?+) AND name = ?
+func Fingerprint(sql string) string {
+	var (
+		builder strings.Builder
+		space   bool
+	)
+	builder.Grow(len(sql))
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = builder.Len() > 0
+			continue
+		case space:
+			builder.WriteByte(' ')
+			space = false
+		}
+
+		switch {
+		case c == '\'':
+			// string literal, quotes are escaped by doubling them
+			for i++; i < len(sql); i++ {
+				if sql[i] == '\'' {
+					if i+1 < len(sql) && sql[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			builder.WriteByte('?')
+		case c == '`' || c == '"':
+			// quoted identifier
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				builder.WriteString(sql[i:])
+				i = len(sql)
+				break
+			}
+			builder.WriteString(sql[i : i+end+2])
+			i += end + 1
+		case (c == '$' || c == '@' || c == ':') && i+1 < len(sql) && isDigit(sql[i+1]) && !isWordChar(prevByte(sql, i)):
+			// numbered placeholder, e.g. $1 of PostgreSQL, :1 of Oracle
+			for i+1 < len(sql) && isDigit(sql[i+1]) {
+				i++
+			}
+			builder.WriteByte('?')
+		case isDigit(c) && !isWordChar(prevByte(sql, i)):
+			// numeric literal
+			for i+1 < len(sql) && (isDigit(sql[i+1]) || sql[i+1] == '.') {
+				i++
+			}
+			builder.WriteByte('?')
+		default:
+			builder.WriteByte(c)
+		}
+	}
+
+	fingerprint := placeholderListRe.ReplaceAllString(builder.String(), "?+")
+	return placeholderRowsRe.ReplaceAllString(fingerprint, "(?+)+")
+}
+
+// loadsAssociation reports whether the fingerprint looks like loading associations of a record, which is a select
+// only conditioned by a primary or foreign key, e.g. `SELECT * FROM pets WHERE user_id = ?`
+func loadsAssociation(fingerprint string) bool {
+	lower := strings.ToLower(fingerprint)
+	if !strings.HasPrefix(lower, "select ") {
+		return false
+	}
+
+	idx := strings.LastIndex(lower, " where ")
+	if idx < 0 {
+		return false
+	}
+
+	where := lower[idx+len(" where "):]
+	for _, keyword := range []string{" order by ", " limit ", " group by ", " for "} {
+		if end := strings.Index(where, keyword); end >= 0 {
+			where = where[:end]
+		}
+	}
+
+	keyed := false
+	for _, condition := range strings.Split(where, " and ") {
+		switch condition = strings.TrimSpace(condition); {
+		case keyConditionRe.MatchString(condition):
+			keyed = true
+		case nullConditionRe.MatchString(condition):
+		default:
+			return false
+		}
+	}
+	return keyed
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func prevByte(sql string, i int) byte {
+	if i == 0 {
+		return 0
+	}
+	return sql[i-1]
+}
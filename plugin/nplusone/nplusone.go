@@ -0,0 +1,181 @@
+// Package nplusone detects N+1 queries in development, statements are normalized to fingerprints after they
+// are built, and statements of the same fingerprint executed more than the threshold times in a scope, which is
+// usually a request, are reported with their call sites.
+//
+//	db.Use(nplusone.New(nplusone.Config{Threshold: 5}))
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		ctx := nplusone.WithScope(r.Context())
+//		db.WithContext(ctx).Find(&users)
+//		for _, user := range users {
+//			// reported from the 6th query, consider db.Preload("Pets").Find(&users)
+//			db.WithContext(ctx).Where("user_id = ?", user.ID).Find(&user.Pets)
+//		}
+//	}
+//
+// Statements without a scope in their context are not counted.
+package nplusone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils"
+)
+
+// ErrNPlusOne statement repeated more than the threshold times in a scope
+var ErrNPlusOne = errors.New("N+1 query detected")
+
+// Config N+1 detector config
+type Config struct {
+	// Threshold statements of a fingerprint executed more than it in a scope are reported, default to 5
+	Threshold int
+	// Error fails repeated statements with ErrNPlusOne after they are executed, which are logged as warnings by default
+	Error bool
+}
+
+// Detection repeated statements of a fingerprint in a scope
+type Detection struct {
+	Fingerprint string
+	// SQL the first statement exceeding the threshold
+	SQL   string
+	Count int
+	// Caller file:line of the first statement exceeding the threshold
+	Caller string
+	// SuggestPreload the fingerprint looks like loading associations of records one by one
+	SuggestPreload bool
+}
+
+func (d Detection) String() string {
+	msg := fmt.Sprintf("%s executed %d times at %s", d.Fingerprint, d.Count, d.Caller)
+	if d.SuggestPreload {
+		msg += ", consider loading the association with Preload or Joins"
+	}
+	return msg
+}
+
+type scopeKey struct{}
+
+type scope struct {
+	mu         sync.Mutex
+	counts     map[string]int
+	detections []*Detection
+}
+
+// WithScope returns a context with a new scope counting statements executed with it
+func WithScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scopeKey{}, &scope{counts: map[string]int{}})
+}
+
+// Detections returns detections of the scope of ctx
+func Detections(ctx context.Context) []Detection {
+	s, ok := ctx.Value(scopeKey{}).(*scope)
+	if !ok {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	detections := make([]Detection, len(s.detections))
+	for idx, detection := range s.detections {
+		detections[idx] = *detection
+	}
+	return detections
+}
+
+// Detector N+1 detector plugin
+type Detector struct {
+	Config
+}
+
+// New returns a N+1 detector plugin
+func New(config Config) *Detector {
+	if config.Threshold <= 0 {
+		config.Threshold = 5
+	}
+	return &Detector{Config: config}
+}
+
+func (d *Detector) Name() string {
+	return "gorm:nplusone"
+}
+
+func (d *Detector) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().After("*").Register("nplusone:detect", d.detect); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Query().After("*").Register("nplusone:detect", d.detect); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Update().After("*").Register("nplusone:detect", d.detect); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Delete().After("*").Register("nplusone:detect", d.detect); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Row().After("*").Register("nplusone:detect", d.detect); err != nil {
+		return err
+	}
+
+	return db.Callback().Raw().After("*").Register("nplusone:detect", d.detect)
+}
+
+func (d *Detector) detect(db *gorm.DB) {
+	ctx := db.Statement.Context
+	if ctx == nil || db.Statement.SQL.Len() == 0 {
+		return
+	}
+
+	s, ok := ctx.Value(scopeKey{}).(*scope)
+	if !ok {
+		return
+	}
+
+	var (
+		sql         = db.Statement.SQL.String()
+		fingerprint = Fingerprint(sql)
+	)
+
+	s.mu.Lock()
+	s.counts[fingerprint]++
+	count := s.counts[fingerprint]
+
+	var (
+		detected bool
+		report   string
+	)
+	if count > d.Threshold {
+		var detection *Detection
+		for _, v := range s.detections {
+			if v.Fingerprint == fingerprint {
+				detection = v
+			}
+		}
+
+		if detection == nil {
+			detection = &Detection{Fingerprint: fingerprint, SQL: sql, Caller: utils.FileWithLineNum(), SuggestPreload: loadsAssociation(fingerprint)}
+			s.detections = append(s.detections, detection)
+		}
+		detection.Count = count
+		detected, report = true, detection.String()
+	}
+	s.mu.Unlock()
+
+	if !detected {
+		return
+	}
+
+	if d.Error {
+		db.AddError(fmt.Errorf("%w: %s", ErrNPlusOne, report))
+	} else if count == d.Threshold+1 {
+		db.Logger.Warn(ctx, "%v: %s", ErrNPlusOne, report)
+	}
+}
@@ -0,0 +1,69 @@
+package nplusone_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/plugin/nplusone"
+	"gorm.io/gorm/utils/tests"
+)
+
+func TestFingerprint(t *testing.T) {
+	for sql, fingerprint := range map[string]string{
+		"SELECT * FROM `users` WHERE id IN (?,?, ?) AND name = ?":                "SELECT * FROM `users` WHERE id IN (?+) AND name = ?",
+		"SELECT *  FROM users\n WHERE id = $1 AND age > 18.5 AND name = 'it''s'": "SELECT * FROM users WHERE id = ? AND age > ? AND name = ?",
+		"INSERT INTO `users` (`name`,`age`) VALUES (?,?),(?,?),(?,?)":            "INSERT INTO `users` (`name`,`age`) VALUES (?+)+",
+		"SELECT `table1`.`col2` FROM \"table1\" WHERE v1 = 1":                    "SELECT `table1`.`col2` FROM \"table1\" WHERE v1 = ?",
+	} {
+		if got := nplusone.Fingerprint(sql); got != fingerprint {
+			t.Errorf("fingerprint of %q should be %q, got %q", sql, fingerprint, got)
+		}
+	}
+}
+
+func TestDetector(t *testing.T) {
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+	if err := db.Use(nplusone.New(nplusone.Config{Threshold: 2})); err != nil {
+		t.Fatalf("failed to register N+1 detector, got %v", err)
+	}
+
+	ctx := nplusone.WithScope(context.Background())
+	for i := 1; i <= 4; i++ {
+		var pets []tests.Pet
+		db.WithContext(ctx).Where("user_id = ?", i).Find(&pets)
+		db.WithContext(ctx).Where("name = ?", "pet").Find(&pets)
+		db.Where("user_id = ?", i).Find(&pets)
+	}
+
+	detections := nplusone.Detections(ctx)
+	if len(detections) != 2 {
+		t.Fatalf("expects 2 detections, got %+v", detections)
+	}
+
+	if d := detections[0]; d.Count != 4 || !d.SuggestPreload || !strings.Contains(d.Caller, "nplusone_test.go") ||
+		d.Fingerprint != "SELECT * FROM `pets` WHERE user_id = ? AND `pets`.`deleted_at` IS NULL" {
+		t.Errorf("unexpected detection of pets of users %+v", d)
+	}
+
+	if d := detections[1]; d.Count != 4 || d.SuggestPreload {
+		t.Errorf("querying pets by name shouldn't suggest preloading, got %+v", d)
+	}
+
+	db, _ = gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+	db.Use(nplusone.New(nplusone.Config{Threshold: 1, Error: true}))
+
+	ctx = nplusone.WithScope(context.Background())
+	var user tests.User
+	if err := db.WithContext(ctx).First(&user, 1).Error; err != nil {
+		t.Fatalf("first query shouldn't fail, got %v", err)
+	}
+	if err := db.WithContext(ctx).First(&user, 2).Error; !errors.Is(err, nplusone.ErrNPlusOne) {
+		t.Errorf("repeated query should fail, got %v", err)
+	}
+	if d := nplusone.Detections(ctx); len(d) != 1 || !d[0].SuggestPreload {
+		t.Errorf("loading users by primary key should suggest preloading, got %+v", d)
+	}
+}
@@ -0,0 +1,35 @@
+package resolver
+
+import (
+	"math/rand"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// Policy load balancing policy that picks a conn pool from sources or replicas
+type Policy interface {
+	Resolve([]gorm.ConnPool) gorm.ConnPool
+}
+
+// PolicyFunc func as Policy
+type PolicyFunc func([]gorm.ConnPool) gorm.ConnPool
+
+func (f PolicyFunc) Resolve(connPools []gorm.ConnPool) gorm.ConnPool {
+	return f(connPools)
+}
+
+// RandomPolicy picks a random conn pool
+type RandomPolicy struct{}
+
+func (RandomPolicy) Resolve(connPools []gorm.ConnPool) gorm.ConnPool {
+	return connPools[rand.Intn(len(connPools))]
+}
+
+// RoundRobinPolicy returns a policy that picks conn pools in turn
+func RoundRobinPolicy() Policy {
+	var i uint64
+	return PolicyFunc(func(connPools []gorm.ConnPool) gorm.ConnPool {
+		return connPools[(atomic.AddUint64(&i, 1)-1)%uint64(len(connPools))]
+	})
+}
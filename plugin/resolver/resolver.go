@@ -0,0 +1,364 @@
+// Package resolver routes queries to replicas and writes to sources, globally or per model/table.
+//
+//	db.Use(resolver.Register(resolver.Config{
+//		Replicas: []gorm.Dialector{mysql.Open("replica1_dsn"), mysql.Open("replica2_dsn")},
+//		Policy:   resolver.RoundRobinPolicy(),
+//	}).Register(resolver.Config{
+//		Sources:  []gorm.Dialector{mysql.Open("orders_dsn")},
+//		Replicas: []gorm.Dialector{mysql.Open("orders_replica_dsn")},
+//	}, &Order{}, "order_items"))
+//
+//	// force a query to use sources
+//	db.Clauses(resolver.Write).First(&user)
+//
+// The connection of the db is the source if global Sources are not configured. Statements in a transaction
+// are always executed on the connection that began the transaction, which is a global source unless the
+// transaction is begun with Use:
+//
+//	db.Clauses(resolver.Use(&Order{})).Transaction(func(tx *gorm.DB) error {
+//		return tx.Create(&order).Error
+//	})
+//
+// Sources and replicas get prepared statement pools of their own when the db prepares statements.
+package resolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	clauseName = "gorm:resolver"
+	pluginName = "gorm:resolver"
+)
+
+// Operation forces a statement to use sources or replicas, used with db.Clauses
+type Operation string
+
+const (
+	Write Operation = "write"
+	Read  Operation = "read"
+)
+
+func (op Operation) ModifyStatement(stmt *gorm.Statement) {
+	stmt.Clauses[clauseName] = clause.Clause{Name: "", Expression: op}
+}
+
+func (op Operation) Build(clause.Builder) {}
+
+// Use routes transactions begun by the statement to sources of data, which is a model or table name registered
+// with the resolver
+func Use(data interface{}) clause.Expression {
+	return using{data: data}
+}
+
+type using struct {
+	data interface{}
+}
+
+func (u using) ModifyStatement(stmt *gorm.Statement) {
+	r, ok := stmt.DB.Plugins[pluginName].(*Resolver)
+	if !ok {
+		stmt.AddError(errors.New("resolver: plugin is not registered"))
+		return
+	}
+
+	table, ok := u.data.(string)
+	if !ok {
+		dataStmt := &gorm.Statement{DB: stmt.DB}
+		if err := dataStmt.Parse(u.data); err != nil {
+			stmt.AddError(fmt.Errorf("resolver: %w", err))
+			return
+		}
+		table = dataStmt.Table
+	}
+
+	set := r.global
+	if s, ok := r.resolvers[table]; ok {
+		set = s
+	}
+	stmt.ConnPool = r.connPool(stmt.DB, set.pool)
+}
+
+func (using) Build(clause.Builder) {}
+
+// Config sources and replicas of the resolver, sources default to the connection of the db,
+// replicas default to sources, Policy default to RandomPolicy
+type Config struct {
+	Sources  []gorm.Dialector
+	Replicas []gorm.Dialector
+	Policy   Policy
+}
+
+// Resolver read/write splitting plugin
+type Resolver struct {
+	configs   []resolverConfig
+	global    *resolverSet
+	resolvers map[string]*resolverSet
+	// prepared prepared statement pools of connection pools
+	prepared sync.Map
+}
+
+type resolverConfig struct {
+	Config
+	datas []interface{}
+}
+
+type resolverSet struct {
+	sources  []gorm.ConnPool
+	replicas []gorm.ConnPool
+	policy   Policy
+	// pool conn pool of sources, which begins transactions on them
+	pool *sourcePool
+}
+
+// Register returns a resolver with config, config is applied to datas (models or table names),
+// or to all statements if datas is blank
+func Register(config Config, datas ...interface{}) *Resolver {
+	return (&Resolver{}).Register(config, datas...)
+}
+
+// Register register config for datas, see Register
+func (r *Resolver) Register(config Config, datas ...interface{}) *Resolver {
+	r.configs = append(r.configs, resolverConfig{Config: config, datas: datas})
+	return r
+}
+
+func (r *Resolver) Name() string {
+	return pluginName
+}
+
+// Apply implements gorm.Option, so the resolver could be passed to gorm.Open
+func (r *Resolver) Apply(*gorm.Config) error {
+	return nil
+}
+
+// AfterInitialize implements gorm.Option, registers the resolver after db connected
+func (r *Resolver) AfterInitialize(db *gorm.DB) error {
+	if db == nil {
+		return nil
+	}
+	return db.Use(r)
+}
+
+func (r *Resolver) Initialize(db *gorm.DB) error {
+	r.resolvers = map[string]*resolverSet{}
+	for _, config := range r.configs {
+		set, err := r.compile(db, config.Config)
+		if err != nil {
+			return err
+		}
+
+		if len(config.datas) == 0 {
+			r.global = set
+			continue
+		}
+
+		for _, data := range config.datas {
+			if table, ok := data.(string); ok {
+				r.resolvers[table] = set
+			} else {
+				stmt := &gorm.Statement{DB: db}
+				if err := stmt.Parse(data); err != nil {
+					return err
+				}
+				r.resolvers[stmt.Table] = set
+			}
+		}
+	}
+
+	if r.global == nil {
+		r.global = &resolverSet{sources: []gorm.ConnPool{db.ConnPool}, replicas: []gorm.ConnPool{db.ConnPool}, policy: RandomPolicy{}}
+		r.global.pool = &sourcePool{set: r.global}
+	} else if len(r.global.sources) > 1 || r.global.sources[0] != db.ConnPool {
+		// route statements without resolved callbacks and transactions to global sources
+		db.ConnPool = r.global.pool
+		db.Statement.ConnPool = db.ConnPool
+	}
+
+	if err := db.Callback().Query().Before("*").Register("gorm:resolver", r.switchReplica); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Row().Before("*").Register("gorm:resolver", r.switchReplica); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Create().Before("*").Register("gorm:resolver", r.switchSource); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Update().Before("*").Register("gorm:resolver", r.switchSource); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Delete().Before("*").Register("gorm:resolver", r.switchSource); err != nil {
+		return err
+	}
+
+	return db.Callback().Raw().Before("*").Register("gorm:resolver", r.switchSource)
+}
+
+func (r *Resolver) compile(db *gorm.DB, config Config) (*resolverSet, error) {
+	set := &resolverSet{policy: config.Policy}
+	if set.policy == nil {
+		set.policy = RandomPolicy{}
+	}
+
+	var err error
+	if set.sources, err = openConnPools(db, config.Sources); err != nil {
+		return nil, err
+	}
+
+	if set.replicas, err = openConnPools(db, config.Replicas); err != nil {
+		return nil, err
+	}
+
+	if len(set.sources) == 0 {
+		set.sources = []gorm.ConnPool{db.ConnPool}
+	}
+
+	if len(set.replicas) == 0 {
+		set.replicas = set.sources
+	}
+	set.pool = &sourcePool{set: set}
+	return set, nil
+}
+
+func openConnPools(db *gorm.DB, dialectors []gorm.Dialector) ([]gorm.ConnPool, error) {
+	connPools := make([]gorm.ConnPool, 0, len(dialectors))
+	for _, dialector := range dialectors {
+		tx, err := gorm.Open(dialector, &gorm.Config{Logger: db.Logger, DisableAutomaticPing: db.DisableAutomaticPing})
+		if err != nil {
+			return nil, err
+		}
+
+		if tx.ConnPool == nil {
+			return nil, errors.New("resolver: dialector doesn't open a connection")
+		}
+		connPools = append(connPools, tx.ConnPool)
+	}
+	return connPools, nil
+}
+
+func (r *Resolver) resolve(stmt *gorm.Statement) *resolverSet {
+	if set, ok := r.resolvers[stmt.Table]; ok && stmt.Table != "" {
+		return set
+	}
+	return r.global
+}
+
+// connPool returns pool, which is wrapped by a prepared statement pool of its own if db prepares statements, as
+// statements prepared on a connection pool can't be executed on others
+func (r *Resolver) connPool(db *gorm.DB, pool gorm.ConnPool) gorm.ConnPool {
+	if !db.PrepareStmt {
+		return pool
+	} else if _, ok := pool.(*gorm.PreparedStmtDB); ok {
+		return pool
+	}
+
+	if v, ok := r.prepared.Load(pool); ok {
+		return v.(*gorm.PreparedStmtDB)
+	}
+
+	preparedStmt := gorm.NewPreparedStmtDB(pool)
+	preparedStmt.Stmts = gorm.NewStmtCache(db.PrepareStmtMaxSize, db.PrepareStmtTTL)
+	v, _ := r.prepared.LoadOrStore(pool, preparedStmt)
+	return v.(*gorm.PreparedStmtDB)
+}
+
+func (r *Resolver) switchSource(db *gorm.DB) {
+	if db.Error != nil || inTransaction(db.Statement) {
+		return
+	}
+
+	if op, ok := db.Statement.Clauses[clauseName]; ok && op.Expression == Read {
+		r.switchReplica(db)
+		return
+	}
+
+	set := r.resolve(db.Statement)
+	db.Statement.ConnPool = r.connPool(db, set.policy.Resolve(set.sources))
+}
+
+func (r *Resolver) switchReplica(db *gorm.DB) {
+	if db.Error != nil || inTransaction(db.Statement) {
+		return
+	}
+
+	set := r.resolve(db.Statement)
+	if op, ok := db.Statement.Clauses[clauseName]; ok && op.Expression == Write {
+		db.Statement.ConnPool = r.connPool(db, set.policy.Resolve(set.sources))
+		return
+	}
+
+	if _, ok := db.Statement.Clauses["FOR"]; ok {
+		db.Statement.ConnPool = r.connPool(db, set.policy.Resolve(set.sources))
+		return
+	}
+
+	// raw SQL of Row/Rows might not be a query
+	if sql := strings.TrimSpace(db.Statement.SQL.String()); sql != "" {
+		if fields := strings.Fields(sql); !strings.EqualFold(fields[0], "SELECT") && !strings.EqualFold(fields[0], "WITH") {
+			db.Statement.ConnPool = r.connPool(db, set.policy.Resolve(set.sources))
+			return
+		}
+	}
+
+	db.Statement.ConnPool = r.connPool(db, set.policy.Resolve(set.replicas))
+}
+
+func inTransaction(stmt *gorm.Statement) bool {
+	_, ok := stmt.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+// sourcePool conn pool of global sources, transactions begin on a source picked by the policy
+type sourcePool struct {
+	set *resolverSet
+}
+
+func (p *sourcePool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.set.policy.Resolve(p.set.sources).PrepareContext(ctx, query)
+}
+
+func (p *sourcePool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.set.policy.Resolve(p.set.sources).ExecContext(ctx, query, args...)
+}
+
+func (p *sourcePool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.set.policy.Resolve(p.set.sources).QueryContext(ctx, query, args...)
+}
+
+func (p *sourcePool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.set.policy.Resolve(p.set.sources).QueryRowContext(ctx, query, args...)
+}
+
+func (p *sourcePool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	switch beginner := p.set.policy.Resolve(p.set.sources).(type) {
+	case gorm.TxBeginner:
+		return beginner.BeginTx(ctx, opts)
+	case gorm.ConnPoolBeginner:
+		return beginner.BeginTx(ctx, opts)
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+}
+
+func (p *sourcePool) GetDBConn() (*sql.DB, error) {
+	if sqlDB, ok := p.set.sources[0].(*sql.DB); ok {
+		return sqlDB, nil
+	}
+
+	if connector, ok := p.set.sources[0].(gorm.GetDBConnector); ok {
+		return connector.GetDBConn()
+	}
+	return nil, gorm.ErrInvalidDB
+}
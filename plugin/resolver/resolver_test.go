@@ -0,0 +1,125 @@
+package resolver_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/plugin/resolver"
+	"gorm.io/gorm/utils/tests"
+)
+
+type namedPool struct {
+	gorm.ConnPool
+	name string
+}
+
+type txPool struct {
+	namedPool
+}
+
+func (txPool) Commit() error   { return nil }
+func (txPool) Rollback() error { return nil }
+
+func (p *namedPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return &txPool{namedPool{name: p.name + " tx"}}, nil
+}
+
+type namedDialector struct {
+	tests.DummyDialector
+	name string
+}
+
+func (d namedDialector) Initialize(db *gorm.DB) error {
+	db.ConnPool = &namedPool{name: d.name}
+	return d.DummyDialector.Initialize(db)
+}
+
+func TestResolver(t *testing.T) {
+	db, _ := gorm.Open(namedDialector{name: "main"}, &gorm.Config{SkipDefaultTransaction: true})
+
+	var used string
+	record := func(db *gorm.DB) {
+		switch pool := db.Statement.ConnPool.(type) {
+		case *namedPool:
+			used = pool.name
+		case txPool:
+			used = pool.name
+		case *txPool:
+			used = pool.name
+		case *gorm.PreparedStmtDB:
+			used = "prepared " + pool.ConnPool.(*namedPool).name
+		}
+	}
+	db.Callback().Query().Replace("gorm:query", record)
+	db.Callback().Row().Replace("gorm:row", record)
+	db.Callback().Create().Replace("gorm:create", record)
+	db.Callback().Raw().Replace("gorm:raw", record)
+
+	err := db.Use(resolver.Register(resolver.Config{
+		Replicas: []gorm.Dialector{namedDialector{name: "replica1"}, namedDialector{name: "replica2"}},
+		Policy:   resolver.RoundRobinPolicy(),
+	}).Register(resolver.Config{
+		Sources: []gorm.Dialector{namedDialector{name: "pets"}},
+	}, &tests.Pet{}, "toys"))
+	if err != nil {
+		t.Fatalf("failed to register resolver, got %v", err)
+	}
+
+	var user tests.User
+	for _, expected := range []string{"replica1", "replica2", "replica1"} {
+		if db.Find(&user); used != expected {
+			t.Errorf("queries should be balanced between replicas, expects %v, got %v", expected, used)
+		}
+	}
+
+	cases := []struct {
+		name     string
+		run      func()
+		expected string
+	}{
+		{"create", func() { db.Create(&tests.User{}) }, "main"},
+		{"exec", func() { db.Exec("UPDATE users SET age = 1") }, "main"},
+		{"write clause", func() { db.Clauses(resolver.Write).Find(&user) }, "main"},
+		{"locking", func() { db.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&user) }, "main"},
+		{"raw update row", func() { db.Raw("UPDATE users SET age = 1 RETURNING id").Row() }, "main"},
+		{"model source", func() { db.Create(&tests.Pet{}) }, "pets"},
+		{"model replica", func() { db.Find(&[]tests.Pet{}) }, "pets"},
+		{"table", func() { db.Table("toys").Find(&[]map[string]interface{}{}) }, "pets"},
+		{"global transaction", func() {
+			db.Transaction(func(tx *gorm.DB) error { return tx.Create(&tests.Pet{}).Error })
+		}, "main tx"},
+		{"model transaction", func() {
+			db.Clauses(resolver.Use(&tests.Pet{})).Transaction(func(tx *gorm.DB) error { return tx.Create(&tests.Pet{}).Error })
+		}, "pets tx"},
+		{"table transaction", func() {
+			db.Clauses(resolver.Use("toys")).Transaction(func(tx *gorm.DB) error { return tx.Create(&tests.Pet{}).Error })
+		}, "pets tx"},
+		{"prepared source", func() { db.Session(&gorm.Session{PrepareStmt: true}).Create(&tests.Pet{}) }, "prepared pets"},
+		{"prepared replica", func() { db.Session(&gorm.Session{PrepareStmt: true}).Find(&user) }, "prepared replica1"},
+		{"transaction", func() {
+			tx := db.Session(&gorm.Session{})
+			tx.Statement.ConnPool = txPool{namedPool{name: "tx"}}
+			tx.Find(&user)
+		}, "tx"},
+	}
+
+	for _, c := range cases {
+		used = ""
+		if c.run(); used != c.expected {
+			t.Errorf("%v should use %v, got %v", c.name, c.expected, used)
+		}
+	}
+}
+
+func TestRoundRobinPolicy(t *testing.T) {
+	pools := []gorm.ConnPool{&namedPool{name: "a"}, &namedPool{name: "b"}}
+	policy := resolver.RoundRobinPolicy()
+	for i := 0; i < 4; i++ {
+		if pool := policy.Resolve(pools); pool != pools[i%2] {
+			t.Errorf("#%v expects %v, got %v", i, pools[i%2], pool)
+		}
+	}
+}
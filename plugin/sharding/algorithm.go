@@ -0,0 +1,130 @@
+package sharding
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Algorithm returns the table suffix of a sharding key value
+type Algorithm func(value interface{}) (suffix string, err error)
+
+// Modulo shards integer keys by |key| % shards, suffixes are zero padded, e.g. _00 to _63 for 64 shards
+func Modulo(shards uint) Algorithm {
+	if shards == 0 {
+		return zeroShards
+	}
+
+	format := suffixFormat(shards)
+	return func(value interface{}) (string, error) {
+		v, err := toUint64(value)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(format, v%uint64(shards)), nil
+	}
+}
+
+// Hash shards keys by FNV-1a hash of the key, for string keys
+func Hash(shards uint) Algorithm {
+	if shards == 0 {
+		return zeroShards
+	}
+
+	format := suffixFormat(shards)
+	return func(value interface{}) (string, error) {
+		value, err := indirect(value)
+		if err != nil {
+			return "", err
+		}
+
+		h := fnv.New32a()
+		fmt.Fprint(h, value)
+		return fmt.Sprintf(format, uint64(h.Sum32())%uint64(shards)), nil
+	}
+}
+
+// DateRange shards time keys by time formatted with layout, e.g. "200601" for monthly tables like orders_202401
+func DateRange(layout string) Algorithm {
+	return func(value interface{}) (string, error) {
+		value, err := indirect(value)
+		if err != nil {
+			return "", err
+		}
+
+		t, ok := value.(time.Time)
+		if !ok {
+			return "", fmt.Errorf("sharding: date range key should be time.Time, got %T", value)
+		}
+		return "_" + t.Format(layout), nil
+	}
+}
+
+// zeroShards algorithm of Modulo and Hash with zero shards, which is rejected by Initialize
+func zeroShards(interface{}) (string, error) {
+	return "", fmt.Errorf("%w: number of shards should be greater than zero", ErrInvalidConfig)
+}
+
+func isZeroShards(algorithm Algorithm) bool {
+	return reflect.ValueOf(algorithm).Pointer() == reflect.ValueOf(zeroShards).Pointer()
+}
+
+func suffixFormat(shards uint) string {
+	return "_%0" + strconv.Itoa(len(strconv.FormatUint(uint64(shards-1), 10))) + "d"
+}
+
+func indirect(value interface{}) (interface{}, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	for reflectValue.Kind() == reflect.Ptr {
+		if reflectValue.IsNil() {
+			return nil, fmt.Errorf("sharding: key value is nil")
+		}
+		reflectValue = reflectValue.Elem()
+	}
+
+	if !reflectValue.IsValid() {
+		return nil, fmt.Errorf("sharding: key value is nil")
+	}
+	return reflectValue.Interface(), nil
+}
+
+// toUint64 returns the absolute value of integer keys
+func toUint64(value interface{}) (uint64, error) {
+	value, err := indirect(value)
+	if err != nil {
+		return 0, err
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return abs(reflectValue.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflectValue.Uint(), nil
+	case reflect.String:
+		if v, err := strconv.ParseInt(reflectValue.String(), 10, 64); err == nil {
+			return abs(v), nil
+		}
+		return strconv.ParseUint(reflectValue.String(), 10, 64)
+	}
+	return 0, fmt.Errorf("sharding: modulo key should be an integer, got %T", value)
+}
+
+// abs absolute value of v, which doesn't overflow for math.MinInt64
+func abs(v int64) uint64 {
+	if v < 0 {
+		return uint64(-(v + 1)) + 1
+	}
+	return uint64(v)
+}
@@ -0,0 +1,291 @@
+// Package sharding rewrites table names of sharded tables by the sharding key value found in
+// conditions, the model or created values of statements.
+//
+//	db.Use(sharding.Register(sharding.Config{
+//		ShardingKey: "user_id",
+//		Algorithm:   sharding.Modulo(64),
+//		IDGenerator: snowflake,
+//	}, &Order{}))
+//
+//	db.Create(&Order{UserID: 3})              // INSERT INTO orders_03 ...
+//	db.Where("user_id = ?", 3).Find(&orders) // SELECT * FROM orders_03 WHERE user_id = 3
+//	db.Clauses(sharding.AllowMissingKey).Find(&orders) // SELECT * FROM orders
+//
+// Only equality conditions of the sharding key combined with AND are recognized.
+package sharding
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// ErrMissingShardingKey sharding key not found in statement
+	ErrMissingShardingKey = errors.New("sharding key required")
+	// ErrCrossShard values of the statement belong to different shards
+	ErrCrossShard = errors.New("cross shard statement is not supported")
+	// ErrInvalidConfig sharding config rejected by Initialize
+	ErrInvalidConfig = errors.New("invalid sharding config")
+)
+
+const clauseName = "gorm:sharding"
+
+type allowMissingKey struct{}
+
+func (allowMissingKey) ModifyStatement(stmt *gorm.Statement) {
+	stmt.Clauses[clauseName] = clause.Clause{Name: ""}
+}
+
+func (allowMissingKey) Build(clause.Builder) {}
+
+// AllowMissingKey allows a statement without sharding key, which is executed on the unsharded table name
+var AllowMissingKey = allowMissingKey{}
+
+// Config sharding config of tables
+type Config struct {
+	// ShardingKey column name of the sharding key
+	ShardingKey string
+	// Algorithm returns the table suffix of a sharding key value
+	Algorithm Algorithm
+	// IDGenerator generates primary keys of created records if not blank
+	IDGenerator IDGenerator
+}
+
+// Sharding sharding plugin
+type Sharding struct {
+	configs map[string]Config
+	datas   []shardingData
+}
+
+type shardingData struct {
+	config Config
+	tables []interface{}
+}
+
+// Register returns a sharding plugin that shards tables (models or table names) with config
+func Register(config Config, tables ...interface{}) *Sharding {
+	return (&Sharding{}).Register(config, tables...)
+}
+
+// Register register config for tables, see Register
+func (s *Sharding) Register(config Config, tables ...interface{}) *Sharding {
+	s.datas = append(s.datas, shardingData{config: config, tables: tables})
+	return s
+}
+
+func (s *Sharding) Name() string {
+	return "gorm:sharding"
+}
+
+func (s *Sharding) Initialize(db *gorm.DB) error {
+	s.configs = map[string]Config{}
+	for _, data := range s.datas {
+		if data.config.ShardingKey == "" || data.config.Algorithm == nil {
+			return fmt.Errorf("%w: sharding key and algorithm are required", ErrInvalidConfig)
+		} else if isZeroShards(data.config.Algorithm) {
+			return fmt.Errorf("%w: number of shards of %s should be greater than zero", ErrInvalidConfig, data.config.ShardingKey)
+		}
+
+		for _, table := range data.tables {
+			if name, ok := table.(string); ok {
+				s.configs[name] = data.config
+			} else {
+				stmt := &gorm.Statement{DB: db}
+				if err := stmt.Parse(table); err != nil {
+					return err
+				}
+				s.configs[stmt.Table] = data.config
+			}
+		}
+	}
+
+	if err := db.Callback().Create().Before("gorm:create").Register("gorm:sharding", s.switchTable(opCreate)); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Query().Before("gorm:query").Register("gorm:sharding", s.switchTable(opQuery)); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Update().Before("gorm:update").Register("gorm:sharding", s.switchTable(opWrite)); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Delete().Before("gorm:delete").Register("gorm:sharding", s.switchTable(opWrite)); err != nil {
+		return err
+	}
+
+	return db.Callback().Row().Before("gorm:row").Register("gorm:sharding", s.switchTable(opQuery))
+}
+
+// RequiresCreateCallbacks implements gorm.BulkLoadPlugin, rows of sharded tables are loaded by create callbacks,
+// which generate their IDs and switch their tables
+func (s *Sharding) RequiresCreateCallbacks(stmt *gorm.Statement) bool {
+	_, ok := s.configs[stmt.Table]
+	return ok
+}
+
+type operation int
+
+const (
+	opQuery operation = iota
+	opCreate
+	opWrite
+)
+
+func (s *Sharding) switchTable(op operation) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		config, ok := s.configs[db.Statement.Table]
+		if !ok || db.Error != nil || db.Statement.SQL.Len() > 0 {
+			return
+		}
+
+		if op == opCreate && config.IDGenerator != nil && db.Statement.Schema != nil {
+			generateIDs(db, config.IDGenerator)
+		}
+
+		s.rewriteTable(db, config, op)
+	}
+}
+
+func (s *Sharding) rewriteTable(db *gorm.DB, config Config, op operation) {
+	values, err := shardingValues(db.Statement, config.ShardingKey, op)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+
+	if len(values) == 0 {
+		if _, ok := db.Statement.Clauses[clauseName]; !ok {
+			db.AddError(fmt.Errorf("%w: %s of %s", ErrMissingShardingKey, config.ShardingKey, db.Statement.Table))
+		}
+		return
+	}
+
+	var suffix string
+	for idx, value := range values {
+		v, err := config.Algorithm(value)
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+
+		if idx > 0 && v != suffix {
+			db.AddError(fmt.Errorf("%w: %s of %s", ErrCrossShard, config.ShardingKey, db.Statement.Table))
+			return
+		}
+		suffix = v
+	}
+
+	db.Statement.Table += suffix
+}
+
+// generateIDs fills zero integer primary keys of created values
+func generateIDs(db *gorm.DB, generator IDGenerator) {
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	if field == nil || (field.DataType != schema.Int && field.DataType != schema.Uint) {
+		return
+	}
+
+	fill := func(rv reflect.Value) {
+		if _, isZero := field.ValueOf(db.Statement.Context, rv); isZero {
+			db.AddError(field.Set(db.Statement.Context, rv, generator.NextID()))
+		}
+	}
+
+	switch rv := db.Statement.ReflectValue; rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if elem := reflect.Indirect(rv.Index(i)); elem.Kind() == reflect.Struct {
+				fill(elem)
+			}
+		}
+	case reflect.Struct:
+		fill(rv)
+	}
+}
+
+// shardingValues sharding key values from created values, the model of writes or conditions
+func shardingValues(stmt *gorm.Statement, key string, op operation) ([]interface{}, error) {
+	var values []interface{}
+
+	// dest of queries might be filled with previous results
+	if stmt.Schema != nil && op != opQuery {
+		if field := stmt.Schema.LookUpField(key); field != nil {
+			switch rv := stmt.ReflectValue; rv.Kind() {
+			case reflect.Slice, reflect.Array:
+				for i := 0; i < rv.Len(); i++ {
+					if elem := reflect.Indirect(rv.Index(i)); elem.Kind() == reflect.Struct {
+						if v, isZero := field.ValueOf(stmt.Context, elem); !isZero {
+							values = append(values, v)
+						} else if op == opCreate {
+							return nil, fmt.Errorf("%w: %s of %s", ErrMissingShardingKey, key, stmt.Table)
+						}
+					}
+				}
+			case reflect.Struct:
+				if v, isZero := field.ValueOf(stmt.Context, rv); !isZero {
+					values = append(values, v)
+				}
+			}
+		}
+	}
+
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			values = append(values, conditionValues(where.Exprs, key)...)
+		}
+	}
+	return values, nil
+}
+
+var exprKeyRegexp = regexp.MustCompile("^\\s*[`\"]?(?:\\w+[`\"]?\\.[`\"]?)?(\\w+)[`\"]?\\s*=\\s*\\?\\s*$")
+
+func conditionValues(exprs []clause.Expression, key string) (values []interface{}) {
+	// OR conditions of the first level make other conditions optional
+	for _, expr := range exprs {
+		if or, ok := expr.(clause.OrConditions); ok && len(or.Exprs) == 1 {
+			return nil
+		}
+	}
+
+	for _, expr := range exprs {
+		switch v := expr.(type) {
+		case clause.Eq:
+			if columnName(v.Column) == key {
+				values = append(values, v.Value)
+			}
+		case clause.IN:
+			if columnName(v.Column) == key && len(v.Values) == 1 {
+				values = append(values, v.Values[0])
+			}
+		case clause.Expr:
+			if matches := exprKeyRegexp.FindStringSubmatch(v.SQL); len(matches) == 2 && matches[1] == key && len(v.Vars) == 1 {
+				values = append(values, v.Vars[0])
+			}
+		case clause.AndConditions:
+			values = append(values, conditionValues(v.Exprs, key)...)
+		}
+	}
+	return values
+}
+
+func columnName(column interface{}) string {
+	switch v := column.(type) {
+	case clause.Column:
+		return v.Name
+	case string:
+		if idx := strings.LastIndexByte(v, '.'); idx >= 0 {
+			return v[idx+1:]
+		}
+		return v
+	}
+	return ""
+}
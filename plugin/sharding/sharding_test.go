@@ -0,0 +1,147 @@
+package sharding_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/plugin/sharding"
+	"gorm.io/gorm/utils/tests"
+)
+
+type Order struct {
+	ID      int64
+	UserID  int64
+	Product string
+	Placed  time.Time
+}
+
+func TestSharding(t *testing.T) {
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, SkipDefaultTransaction: true})
+	snowflake, _ := sharding.NewSnowflake(1)
+	if err := db.Use(sharding.Register(sharding.Config{ShardingKey: "user_id", Algorithm: sharding.Modulo(64), IDGenerator: snowflake}, &Order{})); err != nil {
+		t.Fatalf("failed to register sharding, got %v", err)
+	}
+
+	order := Order{UserID: 67, Product: "book"}
+	stmt := db.Create(&order).Statement
+	tests.AssertEqual(t, stmt.SQL.String(), "INSERT INTO `orders_03` (`user_id`,`product`,`placed`,`id`) VALUES (?,?,?,?) RETURNING `id`")
+	if order.ID == 0 {
+		t.Errorf("primary key should be generated")
+	}
+
+	var orders []Order
+	cases := []struct {
+		tx  *gorm.DB
+		sql string
+	}{
+		{db.Where("user_id = ?", 3).Find(&orders), "SELECT * FROM `orders_03` WHERE user_id = ?"},
+		{db.Where(&Order{UserID: 130, Product: "book"}).Find(&orders), "SELECT * FROM `orders_02` WHERE `orders_02`.`user_id` = ? AND `orders_02`.`product` = ?"},
+		{db.Where(map[string]interface{}{"user_id": 64}).First(&order), "SELECT * FROM `orders_00` WHERE `user_id` = ? AND `orders_00`.`id` = ? ORDER BY `orders_00`.`id` LIMIT ?"},
+		{db.Model(&order).Update("product", "pen"), "UPDATE `orders_03` SET `product`=? WHERE `id` = ?"},
+		{db.Where("user_id = ?", 1).Delete(&Order{}), "DELETE FROM `orders_01` WHERE user_id = ?"},
+		{db.Clauses(sharding.AllowMissingKey).Find(&orders), "SELECT * FROM `orders`"},
+	}
+
+	for idx, c := range cases {
+		if c.tx.Error != nil {
+			t.Errorf("#%v failed, got %v", idx, c.tx.Error)
+		}
+		tests.AssertEqual(t, c.tx.Statement.SQL.String(), c.sql)
+	}
+
+	if err := db.Where("product = ?", "book").Find(&orders).Error; !errors.Is(err, sharding.ErrMissingShardingKey) {
+		t.Errorf("query without sharding key should be rejected, got %v", err)
+	}
+
+	if err := db.Where("user_id = ?", 1).Or("user_id = ?", 2).Find(&orders).Error; !errors.Is(err, sharding.ErrMissingShardingKey) {
+		t.Errorf("query with OR conditions should be rejected, got %v", err)
+	}
+
+	if err := db.Create(&[]Order{{UserID: 1}, {UserID: 2}}).Error; !errors.Is(err, sharding.ErrCrossShard) {
+		t.Errorf("create across shards should be rejected, got %v", err)
+	}
+}
+
+func TestAlgorithms(t *testing.T) {
+	placed := time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)
+	userID := uint(130)
+	cases := []struct {
+		algorithm sharding.Algorithm
+		value     interface{}
+		suffix    string
+	}{
+		{sharding.Modulo(64), 67, "_03"},
+		{sharding.Modulo(8), &userID, "_2"},
+		{sharding.Modulo(128), "300", "_044"},
+		{sharding.Modulo(10), int64(math.MinInt64), "_8"},
+		{sharding.Modulo(10), uint64(math.MaxUint64), "_5"},
+		{sharding.Modulo(10), "18446744073709551615", "_5"},
+		{sharding.Hash(16), "jinzhu", "_03"},
+		{sharding.DateRange("200601"), placed, "_202403"},
+	}
+
+	for idx, c := range cases {
+		if suffix, err := c.algorithm(c.value); err != nil || suffix != c.suffix {
+			t.Errorf("#%v expects %v, got %v, %v", idx, c.suffix, suffix, err)
+		}
+	}
+
+	if _, err := sharding.Modulo(64)("abc"); err == nil {
+		t.Errorf("modulo of non integer should fail")
+	}
+
+	if _, err := sharding.Hash(0)("jinzhu"); !errors.Is(err, sharding.ErrInvalidConfig) {
+		t.Errorf("hash of zero shards should fail, got %v", err)
+	}
+
+	db, _ := gorm.Open(tests.DummyDialector{}, nil)
+	if err := db.Use(sharding.Register(sharding.Config{ShardingKey: "user_id", Algorithm: sharding.Modulo(0)}, &Order{})); !errors.Is(err, sharding.ErrInvalidConfig) {
+		t.Errorf("modulo of zero shards should be rejected, got %v", err)
+	}
+}
+
+func TestSnowflake(t *testing.T) {
+	snowflake, err := sharding.NewSnowflake(1023)
+	if err != nil {
+		t.Fatalf("failed to create snowflake, got %v", err)
+	}
+
+	ids := map[int64]bool{}
+	for i := 0; i < 10000; i++ {
+		id := snowflake.NextID()
+		if ids[id] {
+			t.Fatalf("duplicated id %v", id)
+		}
+		ids[id] = true
+	}
+
+	if _, err := sharding.NewSnowflake(1024); err == nil {
+		t.Errorf("node out of range should be rejected")
+	}
+}
+
+func TestShardingBulkLoad(t *testing.T) {
+	dialector := &tests.BulkLoadDialector{}
+	db, _ := gorm.Open(dialector, &gorm.Config{SkipDefaultTransaction: true})
+	if err := db.Use(sharding.Register(sharding.Config{ShardingKey: "user_id", Algorithm: sharding.Modulo(64)}, &Order{})); err != nil {
+		t.Fatalf("failed to register sharding, got %v", err)
+	}
+
+	var tables []string
+	db.Callback().Create().Replace("gorm:create", func(db *gorm.DB) {
+		tables = append(tables, db.Statement.Table)
+		db.RowsAffected = 2
+	})
+
+	if err := db.BulkLoad("", &[]Order{{UserID: 3}, {UserID: 67}}).Error; err != nil {
+		t.Fatalf("failed to bulk load orders, got %v", err)
+	}
+
+	if len(dialector.Rows) != 0 {
+		t.Errorf("rows of sharded tables should not be loaded natively, got %v", dialector.Rows)
+	}
+	tests.AssertEqual(t, tables, []string{"orders_03"})
+}
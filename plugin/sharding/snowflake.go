@@ -0,0 +1,65 @@
+package sharding
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IDGenerator generates primary keys that are unique across shards
+type IDGenerator interface {
+	NextID() int64
+}
+
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	snowflakeMaxNode      = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSequence  = 1<<snowflakeSequenceBits - 1
+)
+
+// snowflakeEpoch 2020-01-01 UTC in milliseconds
+var snowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+
+// Snowflake snowflake ID generator, IDs are composed of 41 bits milliseconds since 2020-01-01,
+// 10 bits node number and 12 bits sequence number, each node should have a unique node number
+type Snowflake struct {
+	node     int64
+	mu       sync.Mutex
+	last     int64
+	sequence int64
+}
+
+// NewSnowflake returns a snowflake ID generator of node, node should be in [0, 1023]
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > snowflakeMaxNode {
+		return nil, fmt.Errorf("sharding: snowflake node should be in [0, %d], got %d", snowflakeMaxNode, node)
+	}
+	return &Snowflake{node: node}, nil
+}
+
+func (s *Snowflake) NextID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	if now < s.last {
+		// clock moved backwards, keep increasing from the last timestamp
+		now = s.last
+	}
+
+	if now == s.last {
+		s.sequence = (s.sequence + 1) & snowflakeMaxSequence
+		if s.sequence == 0 {
+			for now <= s.last {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Now().UnixMilli()
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+
+	s.last = now
+	return (now-snowflakeEpoch)<<(snowflakeNodeBits+snowflakeSequenceBits) | s.node<<snowflakeSequenceBits | s.sequence
+}
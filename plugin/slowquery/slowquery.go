@@ -0,0 +1,235 @@
+// Package slowquery captures query plans of slow queries, queries slower than the threshold are explained again
+// with the EXPLAIN statement of the dialector, which has to implement gorm.ExplainDialector, and the plan is
+// attached to the context of the statement, so the slow SQL log of the logger includes it.
+//
+//	db.Use(slowquery.New(slowquery.Config{Threshold: 200 * time.Millisecond, SampleRate: 0.1, MaxPerMinute: 10}))
+//
+// Only SELECT statements of Find, First, Take, Last, Count and Pluck are explained. Row and Rows are never
+// explained, their rows are still open after the callbacks, writes and statements executed by Exec are never
+// explained either, neither are locking reads. EXPLAIN ANALYZE executes the query again, so it's only used for
+// queries built from models, SQL of Raw might have side effects like nextval or advisory locks, and is explained
+// without ANALYZE. The duration logged includes the EXPLAIN.
+package slowquery
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	startAtKey = "slowquery:start_at"
+	rawKey     = "slowquery:raw"
+	contextKey = "slowquery:context"
+)
+
+// Config slow query analyzer config
+type Config struct {
+	// Threshold queries slower than it are explained, default to 200ms
+	Threshold time.Duration
+	// Analyze explains with EXPLAIN ANALYZE, which executes the query again
+	Analyze bool
+	// SampleRate ratio of slow queries to be explained, between 0 and 1, default to 1
+	SampleRate float64
+	// MaxPerMinute max number of queries to be explained per minute, unlimited if not positive
+	MaxPerMinute int
+}
+
+// Analyzer slow query analyzer plugin
+type Analyzer struct {
+	Config
+
+	mu          sync.Mutex
+	windowStart time.Time
+	explained   int
+}
+
+// New returns a slow query analyzer plugin
+func New(config Config) *Analyzer {
+	if config.Threshold <= 0 {
+		config.Threshold = 200 * time.Millisecond
+	}
+	if config.SampleRate <= 0 || config.SampleRate > 1 {
+		config.SampleRate = 1
+	}
+	return &Analyzer{Config: config}
+}
+
+func (a *Analyzer) Name() string {
+	return "gorm:slowquery"
+}
+
+func (a *Analyzer) Initialize(db *gorm.DB) error {
+	if _, ok := db.Dialector.(gorm.ExplainDialector); !ok {
+		return fmt.Errorf("dialector %s doesn't support EXPLAIN", db.Dialector.Name())
+	}
+
+	callbacks := db.Callback()
+
+	// plans are attached to the context of statements, which are reused by chained statements, so they are
+	// cleared before statements of all processors
+	if err := callbacks.Create().Before("*").Register("slowquery:clear", a.clear); err != nil {
+		return err
+	}
+
+	if err := callbacks.Update().Before("*").Register("slowquery:clear", a.clear); err != nil {
+		return err
+	}
+
+	if err := callbacks.Delete().Before("*").Register("slowquery:clear", a.clear); err != nil {
+		return err
+	}
+
+	if err := callbacks.Row().Before("*").Register("slowquery:clear", a.clear); err != nil {
+		return err
+	}
+
+	if err := callbacks.Raw().Before("*").Register("slowquery:clear", a.clear); err != nil {
+		return err
+	}
+
+	if err := callbacks.Query().Before("*").Register("slowquery:start", a.start); err != nil {
+		return err
+	}
+
+	return callbacks.Query().After("*").Register("slowquery:explain", a.explain)
+}
+
+// clear restores the context of the statement if it has the plan of a previous statement
+func (a *Analyzer) clear(db *gorm.DB) {
+	if logger.QueryPlanFromContext(db.Statement.Context) == "" {
+		return
+	}
+
+	if v, ok := db.InstanceGet(contextKey); ok {
+		if ctx, ok := v.(context.Context); ok && logger.QueryPlanFromContext(ctx) == "" {
+			db.Statement.Context = ctx
+			return
+		}
+	}
+	db.Statement.Context = logger.WithQueryPlan(db.Statement.Context, "")
+}
+
+func (a *Analyzer) start(db *gorm.DB) {
+	a.clear(db)
+	// SQL is built by gorm:query later unless it's set by Raw
+	db.InstanceSet(rawKey, db.Statement.SQL.Len() > 0)
+	db.InstanceSet(startAtKey, time.Now())
+}
+
+func (a *Analyzer) explain(db *gorm.DB) {
+	v, ok := db.InstanceGet(startAtKey)
+	if !ok {
+		return
+	}
+
+	startAt, ok := v.(time.Time)
+	if !ok || time.Since(startAt) <= a.Threshold || db.Error != nil || db.DryRun || !a.explainable(db) || !a.allow() {
+		return
+	}
+
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	analyze := a.Analyze
+	if v, ok := db.InstanceGet(rawKey); ok {
+		if raw, _ := v.(bool); raw {
+			analyze = false
+		}
+	}
+
+	explainSQL := db.Dialector.(gorm.ExplainDialector).ExplainStatement(db.Statement.SQL.String(), analyze)
+	plan, err := queryPlan(ctx, db.Statement.ConnPool, explainSQL, db.Statement.Vars)
+	if err != nil {
+		db.Logger.Warn(ctx, "failed to explain slow query: %v", err)
+		return
+	}
+	db.InstanceSet(contextKey, db.Statement.Context)
+	db.Statement.Context = logger.WithQueryPlan(ctx, plan)
+}
+
+// explainable reports whether the statement is a read only query that is safe to be explained
+func (a *Analyzer) explainable(db *gorm.DB) bool {
+	if _, ok := db.Statement.Clauses["FOR"]; ok {
+		return false
+	}
+
+	query := strings.ToUpper(strings.TrimSpace(db.Statement.SQL.String()))
+	return strings.HasPrefix(query, "SELECT") && !strings.Contains(query, " FOR UPDATE") && !strings.Contains(query, " FOR SHARE")
+}
+
+// allow samples slow queries and limits the number of explained queries per minute
+func (a *Analyzer) allow() bool {
+	if a.SampleRate < 1 && rand.Float64() >= a.SampleRate {
+		return false
+	}
+
+	if a.MaxPerMinute <= 0 {
+		return true
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if now := time.Now(); now.Sub(a.windowStart) >= time.Minute {
+		a.windowStart, a.explained = now, 0
+	}
+
+	if a.explained >= a.MaxPerMinute {
+		return false
+	}
+	a.explained++
+	return true
+}
+
+// queryPlan runs the EXPLAIN statement with the connection of the query, bypassing callbacks, plans of a single
+// column are returned line by line, e.g. PostgreSQL, otherwise columns are joined by tabs after a header line, e.g. MySQL
+func queryPlan(ctx context.Context, connPool gorm.ConnPool, explainSQL string, vars []interface{}) (string, error) {
+	rows, err := connPool.QueryContext(ctx, explainSQL, vars...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	if len(columns) > 1 {
+		lines = append(lines, strings.Join(columns, "\t"))
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for idx := range values {
+		dest[idx] = &values[idx]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+
+		fields := make([]string, len(values))
+		for idx, value := range values {
+			if value.Valid {
+				fields[idx] = value.String
+			} else {
+				fields[idx] = "NULL"
+			}
+		}
+		lines = append(lines, strings.Join(fields, "\t"))
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
@@ -0,0 +1,125 @@
+package slowquery_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/plugin/slowquery"
+	"gorm.io/gorm/utils/tests"
+)
+
+type explainDialector struct {
+	tests.DummyDialector
+}
+
+func (explainDialector) ExplainStatement(sql string, analyze bool) string {
+	if analyze {
+		return "EXPLAIN ANALYZE " + sql
+	}
+	return "EXPLAIN " + sql
+}
+
+// planDriver sql driver returning a query plan for EXPLAIN and no rows for other queries
+type planDriver struct{ queries *[]string }
+
+type planRows struct {
+	columns []string
+	rows    []string
+}
+
+func (d planDriver) Open(string) (driver.Conn, error)             { return d, nil }
+func (d planDriver) Connect(context.Context) (driver.Conn, error) { return d, nil }
+func (d planDriver) Driver() driver.Driver                        { return d }
+func (d planDriver) Prepare(string) (driver.Stmt, error)          { return nil, errors.New("not supported") }
+func (d planDriver) Close() error                                 { return nil }
+func (d planDriver) Begin() (driver.Tx, error)                    { return nil, errors.New("not supported") }
+
+func (d planDriver) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	*d.queries = append(*d.queries, query)
+	if strings.HasPrefix(query, "EXPLAIN") {
+		return &planRows{columns: []string{"QUERY PLAN"}, rows: []string{"Seq Scan on users", "  Filter: (name = 'jinzhu')"}}, nil
+	}
+	return &planRows{columns: []string{"id"}}, nil
+}
+
+func (d planDriver) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	*d.queries = append(*d.queries, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (r *planRows) Columns() []string { return r.columns }
+func (r *planRows) Close() error      { return nil }
+
+func (r *planRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	dest[0], r.rows = r.rows[0], r.rows[1:]
+	return nil
+}
+
+func TestSlowQuery(t *testing.T) {
+	var (
+		queries []string
+		records []logger.Record
+	)
+
+	db, _ := gorm.Open(explainDialector{}, &gorm.Config{
+		ConnPool:               sql.OpenDB(planDriver{queries: &queries}),
+		SkipDefaultTransaction: true,
+		Logger: logger.NewStructured(logger.HandlerFunc(func(ctx context.Context, record logger.Record) error {
+			records = append(records, record)
+			return nil
+		}), logger.StructuredConfig{Config: logger.Config{SlowThreshold: time.Nanosecond, LogLevel: logger.Warn}}),
+	})
+
+	if err := db.Use(slowquery.New(slowquery.Config{Threshold: time.Nanosecond, Analyze: true, MaxPerMinute: 2})); err != nil {
+		t.Fatalf("failed to register slow query analyzer, got %v", err)
+	}
+
+	var (
+		users []tests.User
+		ids   []int
+	)
+	tx := db.Model(&tests.User{}).Where("name = ?", "jinzhu")
+	tx.Find(&users)
+	db.Raw("SELECT nextval('seq')").Find(&ids)
+	db.Where("name = ?", "jinzhu").Find(&users)
+
+	// the plan of the previous query is not logged with statements of the same chain
+	tx.Update("age", 1)
+
+	if rows, err := db.Raw("SELECT * FROM users").Rows(); err == nil {
+		rows.Close()
+	}
+
+	tests.AssertEqual(t, queries, []string{
+		"SELECT * FROM `users` WHERE name = ? AND `users`.`deleted_at` IS NULL",
+		"EXPLAIN ANALYZE SELECT * FROM `users` WHERE name = ? AND `users`.`deleted_at` IS NULL",
+		"SELECT nextval('seq')",
+		"EXPLAIN SELECT nextval('seq')",
+		"SELECT * FROM `users` WHERE name = ? AND `users`.`deleted_at` IS NULL",
+		"UPDATE `users` SET `age`=?,`updated_at`=? WHERE name = ? AND `users`.`deleted_at` IS NULL",
+		"SELECT * FROM users",
+	})
+
+	plans := make([]interface{}, len(records))
+	for idx, record := range records {
+		for _, field := range record.Fields {
+			if field.Key == "plan" {
+				plans[idx] = field.Value
+			}
+		}
+	}
+
+	plan := "Seq Scan on users\n  Filter: (name = 'jinzhu')"
+	tests.AssertEqual(t, plans, []interface{}{plan, plan, nil, nil, nil})
+}
@@ -0,0 +1,141 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MetricKind kind of metrics
+type MetricKind string
+
+const (
+	Histogram MetricKind = "histogram"
+	Counter   MetricKind = "counter"
+	Gauge     MetricKind = "gauge"
+)
+
+// SpanData span recorded by InMemoryExporter
+type SpanData struct {
+	Name       string
+	Parent     *SpanData
+	Attributes map[string]interface{}
+	Err        error
+	StartAt    time.Time
+	EndAt      time.Time
+}
+
+// Measurement metric recorded by InMemoryExporter
+type Measurement struct {
+	Kind       MetricKind
+	Name       string
+	Value      float64
+	Attributes map[string]interface{}
+}
+
+// InMemoryExporter Tracer and Metrics recording spans and metrics in memory, for tests and debugging
+type InMemoryExporter struct {
+	mu           sync.Mutex
+	spans        []*SpanData
+	measurements []Measurement
+}
+
+type memorySpanKey struct{}
+
+type memorySpan struct {
+	exporter *InMemoryExporter
+	data     *SpanData
+}
+
+// NewInMemoryExporter returns an in memory exporter
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// Start implements Tracer interface, spans started with the context of another span are its children
+func (e *InMemoryExporter) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	data := &SpanData{Name: name, Attributes: map[string]interface{}{}, StartAt: time.Now()}
+	if parent, ok := ctx.Value(memorySpanKey{}).(*memorySpan); ok {
+		data.Parent = parent.data
+	}
+
+	span := &memorySpan{exporter: e, data: data}
+	span.SetAttributes(attrs...)
+	return context.WithValue(ctx, memorySpanKey{}, span), span
+}
+
+// SetAttributes implements Span interface
+func (s *memorySpan) SetAttributes(attrs ...Attribute) {
+	s.exporter.mu.Lock()
+	defer s.exporter.mu.Unlock()
+	for _, attr := range attrs {
+		s.data.Attributes[attr.Key] = attr.Value
+	}
+}
+
+// RecordError implements Span interface
+func (s *memorySpan) RecordError(err error) {
+	s.exporter.mu.Lock()
+	defer s.exporter.mu.Unlock()
+	s.data.Err = err
+}
+
+// End implements Span interface, spans are exported when they end
+func (s *memorySpan) End() {
+	s.exporter.mu.Lock()
+	defer s.exporter.mu.Unlock()
+	s.data.EndAt = time.Now()
+	s.exporter.spans = append(s.exporter.spans, s.data)
+}
+
+// ObserveHistogram implements Metrics interface
+func (e *InMemoryExporter) ObserveHistogram(ctx context.Context, name string, value float64, attrs ...Attribute) {
+	e.record(Histogram, name, value, attrs)
+}
+
+// AddCounter implements Metrics interface
+func (e *InMemoryExporter) AddCounter(ctx context.Context, name string, delta int64, attrs ...Attribute) {
+	e.record(Counter, name, float64(delta), attrs)
+}
+
+// SetGauge implements Metrics interface
+func (e *InMemoryExporter) SetGauge(ctx context.Context, name string, value float64, attrs ...Attribute) {
+	e.record(Gauge, name, value, attrs)
+}
+
+func (e *InMemoryExporter) record(kind MetricKind, name string, value float64, attrs []Attribute) {
+	measurement := Measurement{Kind: kind, Name: name, Value: value, Attributes: make(map[string]interface{}, len(attrs))}
+	for _, attr := range attrs {
+		measurement.Attributes[attr.Key] = attr.Value
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.measurements = append(e.measurements, measurement)
+}
+
+// Spans returns ended spans in the order they ended
+func (e *InMemoryExporter) Spans() []*SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*SpanData(nil), e.spans...)
+}
+
+// Measurements returns measurements of name in the order they were recorded, or all measurements if name is empty
+func (e *InMemoryExporter) Measurements(name string) (measurements []Measurement) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, measurement := range e.measurements {
+		if name == "" || measurement.Name == name {
+			measurements = append(measurements, measurement)
+		}
+	}
+	return
+}
+
+// Reset removes recorded spans and measurements
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans, e.measurements = nil, nil
+}
@@ -0,0 +1,244 @@
+// Package telemetry instruments GORM with spans and metrics of statements, tracers and metrics are small
+// interfaces, so adapters of OpenTelemetry, Prometheus or other libraries can be plugged in.
+//
+//	exporter := telemetry.NewInMemoryExporter()
+//	db.Use(telemetry.New(telemetry.Config{Tracer: exporter, Metrics: exporter, DBStatsInterval: 15 * time.Second}))
+//
+// A span is opened for every Create, Query, Update, Delete, Row and Raw operation, recording the table, operation,
+// SQL, rows affected and error, and the duration of the operation is observed in the DurationMetric histogram,
+// failed operations are counted in the ErrorsMetric counter, ErrRecordNotFound is not counted as error.
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// metric names
+const (
+	// DurationMetric histogram of operation durations in milliseconds
+	DurationMetric = "gorm.operation.duration"
+	// ErrorsMetric counter of failed operations
+	ErrorsMetric = "gorm.operation.errors"
+	// DBStatsMetricPrefix prefix of sql.DBStats gauges, e.g. gorm.db.open_connections
+	DBStatsMetricPrefix = "gorm.db."
+)
+
+// attribute keys, following the database semantic conventions of OpenTelemetry
+const (
+	SystemKey       = "db.system"
+	OperationKey    = "db.operation"
+	TableKey        = "db.sql.table"
+	StatementKey    = "db.statement"
+	RowsAffectedKey = "db.rows_affected"
+)
+
+const spanKey = "telemetry:span"
+
+// Attribute key value attribute of spans and metrics
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Tracer starts spans
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span span of an operation
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Metrics records metrics
+type Metrics interface {
+	// ObserveHistogram observes value in the histogram of name
+	ObserveHistogram(ctx context.Context, name string, value float64, attrs ...Attribute)
+	// AddCounter adds delta to the counter of name
+	AddCounter(ctx context.Context, name string, delta int64, attrs ...Attribute)
+	// SetGauge sets the gauge of name to value
+	SetGauge(ctx context.Context, name string, value float64, attrs ...Attribute)
+}
+
+// Config telemetry plugin config
+type Config struct {
+	// Tracer spans are not recorded if it's nil
+	Tracer Tracer
+	// Metrics metrics are not recorded if it's nil
+	Metrics Metrics
+	// DisableStatement doesn't record SQL in spans
+	DisableStatement bool
+	// ExplainStatement records SQL with params inlined by the dialector's Explain, which is logger.ExplainSQL
+	// for most dialectors, params are not recorded by default as they might contain sensitive data
+	ExplainStatement bool
+	// DBStatsInterval interval of exporting sql.DBStats of DB.DB() as gauges, disabled if not positive
+	DBStatsInterval time.Duration
+}
+
+// Telemetry telemetry plugin
+type Telemetry struct {
+	Config
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type operationSpan struct {
+	span    Span
+	ctx     context.Context
+	startAt time.Time
+}
+
+// New returns a telemetry plugin
+func New(config Config) *Telemetry {
+	return &Telemetry{Config: config, stop: make(chan struct{})}
+}
+
+func (t *Telemetry) Name() string {
+	return "gorm:telemetry"
+}
+
+// registerer callback of processors to be registered
+type registerer interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+func (t *Telemetry) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	for _, op := range []struct {
+		name          string
+		before, after registerer
+	}{
+		{name: "create", before: callbacks.Create().Before("*"), after: callbacks.Create().After("*")},
+		{name: "query", before: callbacks.Query().Before("*"), after: callbacks.Query().After("*")},
+		{name: "update", before: callbacks.Update().Before("*"), after: callbacks.Update().After("*")},
+		{name: "delete", before: callbacks.Delete().Before("*"), after: callbacks.Delete().After("*")},
+		{name: "row", before: callbacks.Row().Before("*"), after: callbacks.Row().After("*")},
+		{name: "raw", before: callbacks.Raw().Before("*"), after: callbacks.Raw().After("*")},
+	} {
+		if err := op.before.Register("telemetry:before_"+op.name, t.before(op.name)); err != nil {
+			return err
+		}
+
+		if err := op.after.Register("telemetry:after_"+op.name, t.after(op.name)); err != nil {
+			return err
+		}
+	}
+
+	if t.Metrics != nil && t.DBStatsInterval > 0 {
+		if sqlDB, err := db.DB(); err == nil {
+			go t.exportDBStats(sqlDB)
+		}
+	}
+	return nil
+}
+
+// Close stops exporting sql.DBStats
+func (t *Telemetry) Close() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
+
+func (t *Telemetry) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		op := &operationSpan{ctx: db.Statement.Context, startAt: time.Now()}
+		if t.Tracer != nil {
+			ctx := db.Statement.Context
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			db.Statement.Context, op.span = t.Tracer.Start(ctx, "gorm."+operation,
+				Attribute{Key: SystemKey, Value: db.Dialector.Name()},
+				Attribute{Key: OperationKey, Value: operation},
+			)
+		}
+		db.InstanceSet(spanKey, op)
+	}
+}
+
+func (t *Telemetry) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(spanKey)
+		if !ok {
+			return
+		}
+
+		op, ok := v.(*operationSpan)
+		if !ok {
+			return
+		}
+
+		var (
+			elapsed = time.Since(op.startAt)
+			failed  = db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+			attrs   = []Attribute{{Key: OperationKey, Value: operation}, {Key: TableKey, Value: db.Statement.Table}}
+		)
+
+		if op.span != nil {
+			spanAttrs := append(attrs, Attribute{Key: RowsAffectedKey, Value: db.RowsAffected})
+			if !t.DisableStatement && db.Statement.SQL.Len() > 0 {
+				statement := db.Statement.SQL.String()
+				if t.ExplainStatement {
+					statement = db.Dialector.Explain(statement, db.Statement.Vars...)
+				}
+				spanAttrs = append(spanAttrs, Attribute{Key: StatementKey, Value: statement})
+			}
+			op.span.SetAttributes(spanAttrs...)
+
+			if failed {
+				op.span.RecordError(db.Error)
+			}
+			op.span.End()
+			db.Statement.Context = op.ctx
+		}
+
+		if t.Metrics != nil {
+			ctx := db.Statement.Context
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			t.Metrics.ObserveHistogram(ctx, DurationMetric, float64(elapsed.Nanoseconds())/1e6, attrs...)
+			if failed {
+				t.Metrics.AddCounter(ctx, ErrorsMetric, 1, attrs...)
+			}
+		}
+	}
+}
+
+func (t *Telemetry) exportDBStats(sqlDB *sql.DB) {
+	ticker := time.NewTicker(t.DBStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			RecordDBStats(context.Background(), t.Metrics, sqlDB.Stats())
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// RecordDBStats records stats as gauges prefixed with DBStatsMetricPrefix
+func RecordDBStats(ctx context.Context, metrics Metrics, stats sql.DBStats) {
+	for name, value := range map[string]float64{
+		"max_open_connections": float64(stats.MaxOpenConnections),
+		"open_connections":     float64(stats.OpenConnections),
+		"in_use":               float64(stats.InUse),
+		"idle":                 float64(stats.Idle),
+		"wait_count":           float64(stats.WaitCount),
+		"wait_duration":        float64(stats.WaitDuration.Nanoseconds()) / 1e6,
+		"max_idle_closed":      float64(stats.MaxIdleClosed),
+		"max_idle_time_closed": float64(stats.MaxIdleTimeClosed),
+		"max_lifetime_closed":  float64(stats.MaxLifetimeClosed),
+	} {
+		metrics.SetGauge(ctx, DBStatsMetricPrefix+name, value)
+	}
+}
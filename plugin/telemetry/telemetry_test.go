@@ -0,0 +1,86 @@
+package telemetry_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/plugin/telemetry"
+	"gorm.io/gorm/utils/tests"
+)
+
+func TestTelemetry(t *testing.T) {
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{SkipDefaultTransaction: true})
+
+	// stub database access
+	errFailed := errors.New("failed")
+	db.Callback().Query().Replace("gorm:query", func(db *gorm.DB) {
+		db.Statement.SQL.WriteString("SELECT * FROM `users` WHERE name = ?")
+		db.Statement.Vars = []interface{}{"jinzhu"}
+		db.RowsAffected = 2
+	})
+	db.Callback().Delete().Replace("gorm:delete", func(db *gorm.DB) {
+		db.AddError(errFailed)
+	})
+
+	exporter := telemetry.NewInMemoryExporter()
+	if err := db.Use(telemetry.New(telemetry.Config{Tracer: exporter, Metrics: exporter, ExplainStatement: true})); err != nil {
+		t.Fatalf("failed to register telemetry plugin, got %v", err)
+	}
+
+	ctx, parent := exporter.Start(context.Background(), "handler")
+	tx := db.WithContext(ctx)
+
+	var users []tests.User
+	tx.Find(&users)
+	if tx.Delete(&tests.User{}, 1).Error == nil {
+		t.Fatalf("stubbed delete should fail")
+	}
+	parent.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("expects 3 spans, got %+v", spans)
+	}
+
+	query, del := spans[0], spans[1]
+	if query.Name != "gorm.query" || query.Parent != spans[2] {
+		t.Errorf("query span should be a child of the handler span, got %+v", query)
+	}
+
+	for key, value := range map[string]interface{}{
+		telemetry.OperationKey:    "query",
+		telemetry.TableKey:        "users",
+		telemetry.StatementKey:    `SELECT * FROM ` + "`users`" + ` WHERE name = "jinzhu"`,
+		telemetry.RowsAffectedKey: int64(2),
+	} {
+		if query.Attributes[key] != value {
+			t.Errorf("attribute %v of query span should be %#v, got %#v", key, value, query.Attributes[key])
+		}
+	}
+
+	if del.Name != "gorm.delete" || !errors.Is(del.Err, errFailed) {
+		t.Errorf("delete span should record error, got %+v", del)
+	}
+
+	if durations := exporter.Measurements(telemetry.DurationMetric); len(durations) != 2 || durations[1].Attributes[telemetry.OperationKey] != "delete" {
+		t.Errorf("durations of operations should be observed, got %+v", durations)
+	}
+
+	if errs := exporter.Measurements(telemetry.ErrorsMetric); len(errs) != 1 || errs[0].Value != 1 || errs[0].Attributes[telemetry.TableKey] != "users" {
+		t.Errorf("errors of delete should be counted, got %+v", errs)
+	}
+
+	exporter.Reset()
+	telemetry.RecordDBStats(context.Background(), exporter, sql.DBStats{OpenConnections: 3, InUse: 1})
+	for _, measurement := range exporter.Measurements("") {
+		if measurement.Name == telemetry.DBStatsMetricPrefix+"open_connections" && measurement.Value != 3 {
+			t.Errorf("open connections should be 3, got %+v", measurement)
+		}
+	}
+	if len(exporter.Measurements(telemetry.DBStatsMetricPrefix+"in_use")) != 1 {
+		t.Errorf("db stats should be recorded as gauges")
+	}
+}
@@ -0,0 +1,346 @@
+// Package tenancy isolates rows of tenants, models with a TenantID field are scoped to the tenant of the
+// statement context, which is set with WithTenant.
+//
+//	type Order struct {
+//		ID       uint
+//		TenantID tenancy.TenantID `gorm:"index"`
+//	}
+//
+//	ctx := tenancy.WithTenant(ctx, "tenant-1")
+//	// SELECT * FROM `orders` WHERE `orders`.`tenant_id` = "tenant-1"
+//	db.WithContext(ctx).Find(&orders)
+//	// INSERT INTO `orders` (`tenant_id`) VALUES ("tenant-1")
+//	db.WithContext(ctx).Create(&Order{})
+//
+// Like soft delete, the condition is added by statement modifiers of the field type, so it applies to
+// preloads, joins and associations of tenant models too. Statements without a tenant fail with
+// ErrMissingTenant, use a context returned by WithoutTenant to operate on rows of all tenants.
+//
+// Updates assigning other tenants fail with ErrTenantMismatch, and upserts only update conflicting rows of the
+// tenant, so they fail on MySQL, which can't condition ON DUPLICATE KEY UPDATE.
+package tenancy
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// ErrMissingTenant statement of a tenant model without tenant in its context
+	ErrMissingTenant = errors.New("missing tenant")
+	// ErrTenantMismatch creating records of another tenant
+	ErrTenantMismatch = errors.New("tenant mismatch")
+)
+
+// TenantID tenant field type, records are scoped to the tenant of the statement context
+type TenantID string
+
+type (
+	tenantKey  struct{}
+	withoutKey struct{}
+)
+
+type tenantState struct {
+	tenant  string
+	ok      bool
+	without bool
+}
+
+// WithTenant returns a context scoping tenant models to tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// WithoutTenant returns a context operating on rows of all tenants, tenants of created records are kept
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutKey{}, true)
+}
+
+// TenantFromContext returns the tenant set with WithTenant
+func TenantFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok
+}
+
+func stateOf(stmt *gorm.Statement) tenantState {
+	ctx := stmt.Context
+	if ctx == nil && stmt.DB != nil && stmt.DB.Statement != nil {
+		ctx = stmt.DB.Statement.Context
+	}
+
+	var state tenantState
+	state.tenant, state.ok = TenantFromContext(ctx)
+	if ctx != nil {
+		state.without, _ = ctx.Value(withoutKey{}).(bool)
+	}
+	return state
+}
+
+// Value implements the driver Valuer interface.
+func (t TenantID) Value() (driver.Value, error) {
+	return string(t), nil
+}
+
+func (TenantID) QueryClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{QueryClause{Field: f}}
+}
+
+func (TenantID) UpdateClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{UpdateClause{Field: f}}
+}
+
+func (TenantID) DeleteClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{DeleteClause{Field: f}}
+}
+
+func (TenantID) CreateClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{CreateClause{Field: f}}
+}
+
+// QueryClause scopes queries to the tenant
+type QueryClause struct {
+	Field *schema.Field
+}
+
+func (tc QueryClause) Name() string {
+	return ""
+}
+
+func (tc QueryClause) Build(clause.Builder) {
+}
+
+func (tc QueryClause) MergeClause(*clause.Clause) {
+}
+
+func (tc QueryClause) ModifyStatement(stmt *gorm.Statement) {
+	addTenantCondition(stmt, tc.Field)
+}
+
+// addTenantCondition add condition that field equals to the tenant of the statement context
+func addTenantCondition(stmt *gorm.Statement, field *schema.Field) {
+	if _, ok := stmt.Clauses["tenant_enabled"]; ok {
+		return
+	}
+
+	state := stateOf(stmt)
+	if state.without {
+		return
+	} else if !state.ok {
+		stmt.AddError(fmt.Errorf("%w: %s", ErrMissingTenant, stmt.Table))
+		return
+	}
+
+	stmt.AddAndConditions(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: state.tenant})
+	stmt.Clauses["tenant_enabled"] = clause.Clause{}
+}
+
+// UpdateClause scopes updates to the tenant
+type UpdateClause struct {
+	Field *schema.Field
+}
+
+func (tc UpdateClause) Name() string {
+	return ""
+}
+
+func (tc UpdateClause) Build(clause.Builder) {
+}
+
+func (tc UpdateClause) MergeClause(*clause.Clause) {
+}
+
+func (tc UpdateClause) ModifyStatement(stmt *gorm.Statement) {
+	if stmt.SQL.Len() == 0 {
+		addTenantCondition(stmt, tc.Field)
+		assignTenant(stmt, tc.Field)
+	}
+}
+
+// assignTenant forces assignments of the tenant field to the tenant of the statement context, so updates can't
+// blank the tenant of rows or move them to other tenants
+func assignTenant(stmt *gorm.Statement, field *schema.Field) {
+	state := stateOf(stmt)
+	if state.without || !state.ok {
+		return
+	}
+
+	mismatch := func(value interface{}) {
+		stmt.AddError(fmt.Errorf("%w: updating %s to tenant %v with tenant %s", ErrTenantMismatch, stmt.Table, value, state.tenant))
+	}
+
+	if c, ok := stmt.Clauses["SET"]; ok {
+		if set, ok := c.Expression.(clause.Set); ok {
+			for _, assignment := range set {
+				if assignment.Column.Name == field.DBName {
+					if tenant, ok := tenantOf(assignment.Value); !ok || tenant != state.tenant {
+						mismatch(assignment.Value)
+					}
+				}
+			}
+		}
+	}
+
+	switch dest := stmt.Dest.(type) {
+	case map[string]interface{}:
+		for _, key := range []string{field.Name, field.DBName} {
+			if value, ok := dest[key]; ok {
+				if tenant, ok := tenantOf(value); !ok || tenant != state.tenant {
+					mismatch(value)
+				}
+				dest[key] = TenantID(state.tenant)
+			}
+		}
+	default:
+		rv := reflect.ValueOf(stmt.Dest)
+		for rv.Kind() == reflect.Ptr {
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Struct {
+			return
+		}
+
+		destField := field
+		if rv.Type() != field.Schema.ModelType {
+			destStmt := &gorm.Statement{DB: stmt.DB}
+			if err := destStmt.Parse(stmt.Dest); err != nil {
+				return
+			}
+			if destField = destStmt.Schema.LookUpField(field.DBName); destField == nil {
+				return
+			}
+		}
+
+		value, zero := destField.ValueOf(stmt.Context, rv)
+		if !zero {
+			if tenant, ok := tenantOf(value); !ok || tenant != state.tenant {
+				mismatch(value)
+			}
+		} else if rv.CanAddr() {
+			// Save updates all fields including the zero tenant
+			stmt.AddError(destField.Set(stmt.Context, rv, TenantID(state.tenant)))
+		} else if selectColumns, _ := stmt.SelectAndOmitColumns(false, true); selectColumns[destField.DBName] {
+			mismatch(value)
+		}
+	}
+}
+
+func tenantOf(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case TenantID:
+		return string(v), true
+	case *TenantID:
+		if v != nil {
+			return string(*v), true
+		}
+	case string:
+		return v, true
+	case *string:
+		if v != nil {
+			return *v, true
+		}
+	}
+	return "", false
+}
+
+// DeleteClause scopes deletes to the tenant
+type DeleteClause struct {
+	Field *schema.Field
+}
+
+func (tc DeleteClause) Name() string {
+	return ""
+}
+
+func (tc DeleteClause) Build(clause.Builder) {
+}
+
+func (tc DeleteClause) MergeClause(*clause.Clause) {
+}
+
+func (tc DeleteClause) ModifyStatement(stmt *gorm.Statement) {
+	if stmt.SQL.Len() == 0 {
+		addTenantCondition(stmt, tc.Field)
+	}
+}
+
+// CreateClause sets the tenant of created records
+type CreateClause struct {
+	Field *schema.Field
+}
+
+func (tc CreateClause) Name() string {
+	return ""
+}
+
+func (tc CreateClause) Build(clause.Builder) {
+}
+
+func (tc CreateClause) MergeClause(*clause.Clause) {
+}
+
+func (tc CreateClause) ModifyStatement(stmt *gorm.Statement) {
+	if stmt.SQL.Len() > 0 {
+		return
+	}
+
+	state := stateOf(stmt)
+	if !state.ok {
+		if !state.without {
+			stmt.AddError(fmt.Errorf("%w: %s", ErrMissingTenant, stmt.Table))
+		}
+		return
+	}
+
+	setTenant := func(rv reflect.Value) {
+		value, zero := tc.Field.ValueOf(stmt.Context, rv)
+		if zero {
+			stmt.AddError(tc.Field.Set(stmt.Context, rv, TenantID(state.tenant)))
+		} else if tenant, _ := value.(TenantID); string(tenant) != state.tenant && !state.without {
+			stmt.AddError(fmt.Errorf("%w: creating %s of tenant %s with tenant %s", ErrTenantMismatch, stmt.Table, tenant, state.tenant))
+		}
+	}
+
+	if c, ok := stmt.Clauses["ON CONFLICT"]; ok && !state.without {
+		if onConflict, ok := c.Expression.(clause.OnConflict); ok && !onConflict.DoNothing {
+			// conflicting rows may belong to other tenants, MySQL can't condition ON DUPLICATE KEY UPDATE
+			if stmt.Dialector.Name() == "mysql" {
+				stmt.AddError(fmt.Errorf("%w: upserting %s is not supported by mysql", ErrTenantMismatch, stmt.Table))
+				return
+			}
+
+			onConflict.Where.Exprs = append(onConflict.Where.Exprs, clause.Eq{
+				Column: clause.Column{Table: clause.CurrentTable, Name: tc.Field.DBName}, Value: state.tenant,
+			})
+			c.Expression = onConflict
+			stmt.Clauses["ON CONFLICT"] = c
+		}
+	}
+
+	switch rv := reflect.Indirect(stmt.ReflectValue); rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if elem := reflect.Indirect(rv.Index(i)); elem.Kind() == reflect.Struct {
+				setTenant(elem)
+			}
+		}
+	case reflect.Struct:
+		setTenant(rv)
+	case reflect.Map:
+		if values, ok := stmt.Dest.(map[string]interface{}); ok {
+			_, hasName := values[tc.Field.Name]
+			if _, hasDBName := values[tc.Field.DBName]; !hasName && !hasDBName {
+				values[tc.Field.DBName] = TenantID(state.tenant)
+			}
+		}
+	}
+}
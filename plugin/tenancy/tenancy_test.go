@@ -0,0 +1,148 @@
+package tenancy_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/plugin/tenancy"
+	"gorm.io/gorm/utils/tests"
+)
+
+type Company struct {
+	ID       uint
+	TenantID tenancy.TenantID
+	Name     string
+}
+
+type Employee struct {
+	ID        uint
+	TenantID  tenancy.TenantID
+	Name      string
+	CompanyID uint
+	Company   Company
+}
+
+func TestTenancy(t *testing.T) {
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, SkipDefaultTransaction: true})
+	tx := db.WithContext(tenancy.WithTenant(context.Background(), "t1"))
+
+	employee := Employee{Name: "jinzhu"}
+	stmt := tx.Omit("Company").Create(&employee).Statement
+	tests.AssertEqual(t, stmt.SQL.String(), "INSERT INTO `employees` (`tenant_id`,`name`,`company_id`) VALUES (?,?,?) RETURNING `id`")
+	tests.AssertEqual(t, employee.TenantID, tenancy.TenantID("t1"))
+
+	var employees []Employee
+	cases := []struct {
+		tx   *gorm.DB
+		sql  string
+		vars []interface{}
+	}{
+		{tx.Find(&employees), "SELECT * FROM `employees` WHERE `employees`.`tenant_id` = ?", []interface{}{"t1"}},
+		{tx.Where("name = ?", "a").Or("name = ?", "b").Find(&employees), "SELECT * FROM `employees` WHERE (name = ? OR name = ?) AND `employees`.`tenant_id` = ?", []interface{}{"a", "b", "t1"}},
+		{tx.Joins("Company").Find(&employees), "SELECT `employees`.`id`,`employees`.`tenant_id`,`employees`.`name`,`employees`.`company_id`,`Company`.`id` AS `Company__id`,`Company`.`tenant_id` AS `Company__tenant_id`,`Company`.`name` AS `Company__name` FROM `employees` LEFT JOIN `companies` `Company` ON `employees`.`company_id` = `Company`.`id` AND `Company`.`tenant_id` = ? WHERE `employees`.`tenant_id` = ?", []interface{}{"t1", "t1"}},
+		{tx.Model(&Employee{ID: 1}).Update("name", "hello"), "UPDATE `employees` SET `name`=? WHERE `employees`.`tenant_id` = ? AND `id` = ?", []interface{}{"hello", "t1", uint(1)}},
+		{tx.Delete(&Employee{ID: 1}), "DELETE FROM `employees` WHERE `employees`.`tenant_id` = ? AND `employees`.`id` = ?", []interface{}{"t1", uint(1)}},
+		{tx.Or("name = ?", "a").Find(&employees), "SELECT * FROM `employees` WHERE name = ? AND `employees`.`tenant_id` = ?", []interface{}{"a", "t1"}},
+		{tx.Model(&Employee{ID: 1}).Or("name = ?", "a").Update("name", "hello"), "UPDATE `employees` SET `name`=? WHERE name = ? AND `employees`.`tenant_id` = ? AND `id` = ?", []interface{}{"hello", "a", "t1", uint(1)}},
+		{tx.Or("name = ?", "a").Delete(&Employee{ID: 1}), "DELETE FROM `employees` WHERE name = ? AND `employees`.`tenant_id` = ? AND `employees`.`id` = ?", []interface{}{"a", "t1", uint(1)}},
+		{db.WithContext(tenancy.WithoutTenant(context.Background())).Find(&employees), "SELECT * FROM `employees`", nil},
+	}
+
+	for idx, c := range cases {
+		if c.tx.Error != nil {
+			t.Errorf("#%v failed, got %v", idx, c.tx.Error)
+		}
+		tests.AssertEqual(t, c.tx.Statement.SQL.String(), c.sql)
+		if len(c.vars) > 0 {
+			tests.AssertEqual(t, c.tx.Statement.Vars, c.vars)
+		}
+	}
+
+	if err := db.Find(&employees).Error; !errors.Is(err, tenancy.ErrMissingTenant) {
+		t.Errorf("query without tenant should be rejected, got %v", err)
+	}
+
+	if err := db.Model(&Employee{ID: 1}).Update("name", "hello").Error; !errors.Is(err, tenancy.ErrMissingTenant) {
+		t.Errorf("update without tenant should be rejected, got %v", err)
+	}
+
+	if err := db.Create(&Employee{Name: "jinzhu"}).Error; !errors.Is(err, tenancy.ErrMissingTenant) {
+		t.Errorf("create without tenant should be rejected, got %v", err)
+	}
+
+	if err := tx.Omit("Company").Create(&Employee{TenantID: "t2"}).Error; !errors.Is(err, tenancy.ErrTenantMismatch) {
+		t.Errorf("create records of other tenants should be rejected, got %v", err)
+	}
+}
+
+type noRowsPool struct {
+	gorm.ConnPool
+	sqls []string
+}
+
+func (p *noRowsPool) ExecContext(_ context.Context, sql string, _ ...interface{}) (sql.Result, error) {
+	p.sqls = append(p.sqls, sql)
+	return driver.RowsAffected(0), nil
+}
+
+func (p *noRowsPool) QueryContext(_ context.Context, sql string, _ ...interface{}) (*sql.Rows, error) {
+	p.sqls = append(p.sqls, sql)
+	return nil, errors.New("no rows")
+}
+
+func TestTenancyAssignments(t *testing.T) {
+	pool := &noRowsPool{}
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{ConnPool: pool, SkipDefaultTransaction: true})
+	tx := db.WithContext(tenancy.WithTenant(context.Background(), "t1"))
+
+	employee := Employee{ID: 5, Name: "x"}
+	tx.Omit("Company").Save(&employee)
+	tests.AssertEqual(t, pool.sqls, []string{
+		"UPDATE `employees` SET `tenant_id`=?,`name`=?,`company_id`=? WHERE `employees`.`tenant_id` = ? AND `id` = ?",
+		"INSERT INTO `employees` (`tenant_id`,`name`,`company_id`,`id`) VALUES (?,?,?,?) ON CONFLICT (`id`) DO UPDATE SET `tenant_id`=`excluded`.`tenant_id`,`name`=`excluded`.`name`,`company_id`=`excluded`.`company_id` WHERE `employees`.`tenant_id` = ?  RETURNING `id`",
+	})
+	tests.AssertEqual(t, employee.TenantID, tenancy.TenantID("t1"))
+
+	if err := tx.Model(&Employee{ID: 5}).Update("tenant_id", "t2").Error; !errors.Is(err, tenancy.ErrTenantMismatch) {
+		t.Errorf("moving rows to other tenants should be rejected, got %v", err)
+	}
+
+	if err := tx.Model(&Employee{ID: 5}).Updates(map[string]interface{}{"TenantID": tenancy.TenantID("t2")}).Error; !errors.Is(err, tenancy.ErrTenantMismatch) {
+		t.Errorf("moving rows to other tenants with map should be rejected, got %v", err)
+	}
+
+	if err := tx.Model(&Employee{ID: 5}).Updates(Employee{TenantID: "t2"}).Error; !errors.Is(err, tenancy.ErrTenantMismatch) {
+		t.Errorf("moving rows to other tenants with struct should be rejected, got %v", err)
+	}
+
+	dryRun := tx.Session(&gorm.Session{DryRun: true})
+	stmt := dryRun.Model(&Employee{ID: 5}).Update("tenant_id", "t1").Statement
+	tests.AssertEqual(t, stmt.SQL.String(), "UPDATE `employees` SET `tenant_id`=? WHERE `employees`.`tenant_id` = ? AND `id` = ?")
+
+	stmt = dryRun.Omit("Company").Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"name"})}).Create(&Employee{ID: 5, Name: "x"}).Statement
+	tests.AssertEqual(t, stmt.SQL.String(), "INSERT INTO `employees` (`tenant_id`,`name`,`company_id`,`id`) VALUES (?,?,?,?) ON CONFLICT (`id`) DO UPDATE SET `name`=`excluded`.`name` WHERE `employees`.`tenant_id` = ?  RETURNING `id`")
+}
+
+func TestTenancyBulkLoad(t *testing.T) {
+	dialector := &tests.BulkLoadDialector{}
+	db, _ := gorm.Open(dialector, &gorm.Config{SkipDefaultTransaction: true})
+	tx := db.WithContext(tenancy.WithTenant(context.Background(), "t1"))
+
+	if err := tx.BulkLoad("", &[]Company{{Name: "a"}, {Name: "b"}}).Error; err != nil {
+		t.Fatalf("failed to bulk load companies, got %v", err)
+	}
+	tests.AssertEqual(t, dialector.Rows["companies"], [][]interface{}{{"t1", "a"}, {"t1", "b"}})
+
+	if err := tx.BulkLoad("", &[]Company{{TenantID: "t2", Name: "c"}}).Error; !errors.Is(err, tenancy.ErrTenantMismatch) {
+		t.Errorf("bulk load records of other tenants should be rejected, got %v", err)
+	}
+
+	if err := db.BulkLoad("", &[]Company{{Name: "d"}}).Error; !errors.Is(err, tenancy.ErrMissingTenant) {
+		t.Errorf("bulk load without tenant should be rejected, got %v", err)
+	}
+}
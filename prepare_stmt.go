@@ -1,12 +1,15 @@
 package gorm
 
 import (
+	"container/list"
 	"context"
 	"database/sql"
 	"database/sql/driver"
 	"errors"
 	"reflect"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Stmt struct {
@@ -14,19 +17,250 @@ type Stmt struct {
 	Transaction bool
 	prepared    chan struct{}
 	prepareErr  error
+
+	// refs number of callers using the statement, removed statements are closed when it drops to 0
+	mu      sync.Mutex
+	refs    int
+	removed bool
+}
+
+// acquire marks the statement in use, it's not closed until released
+func (stmt *Stmt) acquire() {
+	stmt.mu.Lock()
+	stmt.refs++
+	stmt.mu.Unlock()
+}
+
+// release releases the statement acquired, which is closed if it's removed from the cache and not in use
+func (stmt *Stmt) release() {
+	stmt.mu.Lock()
+	stmt.refs--
+	closable := stmt.removed && stmt.refs <= 0
+	stmt.mu.Unlock()
+
+	if closable {
+		stmt.close()
+	}
+}
+
+// remove marks the statement removed from the cache, which is closed if it's not in use
+func (stmt *Stmt) remove() {
+	stmt.mu.Lock()
+	closable := !stmt.removed && stmt.refs <= 0
+	stmt.removed = true
+	stmt.mu.Unlock()
+
+	if closable {
+		stmt.close()
+	}
+}
+
+// close closes the statement after its preparation completes
+func (stmt *Stmt) close() {
+	go func() {
+		<-stmt.prepared
+		if stmt.Stmt != nil {
+			_ = stmt.Stmt.Close()
+		}
+	}()
+}
+
+// StmtCache LRU cache of prepared statements keyed by SQL, bounded by size and TTL if they are positive,
+// evicted statements are closed after their preparation completes. The TTL is refreshed whenever a statement is
+// used, so statements are ordered by expiration as well as by use
+type StmtCache struct {
+	hits, misses, evictions int64
+
+	maxSize int
+	ttl     time.Duration
+	mu      sync.Mutex
+	ll      *list.List
+	items   map[string]*list.Element
+}
+
+// StmtCacheStats counters of StmtCache
+type StmtCacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+}
+
+type stmtCacheEntry struct {
+	key       string
+	stmt      *Stmt
+	expiresAt time.Time
+}
+
+// DefaultStmtCacheSize max number of statements of StmtCache if the size is zero
+const DefaultStmtCacheSize = 1000
+
+// NewStmtCache returns a prepared statement cache of at most maxSize statements expiring after unused for ttl,
+// maxSize defaults to DefaultStmtCacheSize if it's zero, the cache is unbounded if it's negative
+func NewStmtCache(maxSize int, ttl time.Duration) *StmtCache {
+	if maxSize == 0 {
+		maxSize = DefaultStmtCacheSize
+	}
+	return &StmtCache{maxSize: maxSize, ttl: ttl, ll: list.New(), items: map[string]*list.Element{}}
+}
+
+// Get returns the statement of key and marks it as recently used, the statement might be closed after it's evicted
+func (c *StmtCache) Get(key string) (*Stmt, bool) {
+	stmt, ok := c.get(key, false)
+	if ok {
+		atomic.AddInt64(&c.hits, 1)
+	} else {
+		atomic.AddInt64(&c.misses, 1)
+	}
+	return stmt, ok
+}
+
+// get returns the statement of key without counting hits and misses, the statement is acquired if acquire is true,
+// which has to be released after used
+func (c *StmtCache) get(key string, acquire bool) (*Stmt, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*stmtCacheEntry)
+		if now := time.Now(); entry.expiresAt.IsZero() || now.Before(entry.expiresAt) {
+			if c.ttl > 0 {
+				entry.expiresAt = now.Add(c.ttl)
+			}
+			c.ll.MoveToFront(elem)
+			if acquire {
+				entry.stmt.acquire()
+			}
+			return entry.stmt, true
+		}
+		c.evict(elem)
+	}
+	return nil, false
+}
+
+// Set caches the statement of key, evicting expired and least recently used statements beyond the size, replaced
+// and evicted statements are closed after they are released by callers using them
+func (c *StmtCache) Set(key string, stmt *Stmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &stmtCacheEntry{key: key, stmt: stmt}
+	if c.ttl > 0 {
+		entry.expiresAt = time.Now().Add(c.ttl)
+	}
+
+	if elem, ok := c.items[key]; ok {
+		if old := elem.Value.(*stmtCacheEntry).stmt; old != stmt {
+			old.remove()
+		}
+		elem.Value = entry
+		c.ll.MoveToFront(elem)
+	} else {
+		c.items[key] = c.ll.PushFront(entry)
+	}
+
+	// statements are ordered by expiration, expired ones are at the back
+	now := time.Now()
+	for elem := c.ll.Back(); elem != nil && elem != c.ll.Front(); elem = c.ll.Back() {
+		if expiresAt := elem.Value.(*stmtCacheEntry).expiresAt; (c.maxSize <= 0 || c.ll.Len() <= c.maxSize) && (expiresAt.IsZero() || now.Before(expiresAt)) {
+			break
+		}
+		c.evict(elem)
+	}
+}
+
+// Delete removes the statement of key, which is closed after it's released by callers using it
+func (c *StmtCache) Delete(key string) {
+	c.delete(key, nil)
+}
+
+// delete removes the statement of key if it's stmt or stmt is nil
+func (c *StmtCache) delete(key string, stmt *Stmt) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		if entry := elem.Value.(*stmtCacheEntry); stmt == nil || entry.stmt == stmt {
+			c.ll.Remove(elem)
+			delete(c.items, key)
+			entry.stmt.remove()
+		}
+	}
+}
+
+// Keys returns SQL of cached statements from the most recently used
+func (c *StmtCache) Keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]string, 0, c.ll.Len())
+	for elem := c.ll.Front(); elem != nil; elem = elem.Next() {
+		keys = append(keys, elem.Value.(*stmtCacheEntry).key)
+	}
+	return keys
+}
+
+// Len returns the number of cached statements
+func (c *StmtCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Stats returns hit, miss and eviction counters
+func (c *StmtCache) Stats() StmtCacheStats {
+	return StmtCacheStats{
+		Hits:      atomic.LoadInt64(&c.hits),
+		Misses:    atomic.LoadInt64(&c.misses),
+		Evictions: atomic.LoadInt64(&c.evictions),
+	}
+}
+
+// closeAll removes all statements, which are closed after they are released
+func (c *StmtCache) closeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for elem := c.ll.Front(); elem != nil; elem = elem.Next() {
+		elem.Value.(*stmtCacheEntry).stmt.remove()
+	}
+	c.ll.Init()
+	c.items = map[string]*list.Element{}
+}
+
+func (c *StmtCache) evict(elem *list.Element) {
+	entry := elem.Value.(*stmtCacheEntry)
+	c.ll.Remove(elem)
+	delete(c.items, entry.key)
+	entry.stmt.remove()
+	atomic.AddInt64(&c.evictions, 1)
 }
 
 type PreparedStmtDB struct {
-	Stmts map[string]*Stmt
+	// Stmts cached statements, which is a *StmtCache bounded by PrepareStmtMaxSize and PrepareStmtTTL instead of
+	// map[string]*Stmt since statements are evicted, access statements with Stmts.Get, Stmts.Keys and Stmts.Delete
+	Stmts *StmtCache
 	Mux   *sync.RWMutex
 	ConnPool
+
+	maxSize int
+	ttl     time.Duration
 }
 
 func NewPreparedStmtDB(connPool ConnPool) *PreparedStmtDB {
+	return newPreparedStmtDB(connPool, 0, 0)
+}
+
+func newPreparedStmtDB(connPool ConnPool, maxSize int, ttl time.Duration) *PreparedStmtDB {
 	return &PreparedStmtDB{
 		ConnPool: connPool,
-		Stmts:    make(map[string]*Stmt),
+		Stmts:    NewStmtCache(maxSize, ttl),
 		Mux:      &sync.RWMutex{},
+		maxSize:  maxSize,
+		ttl:      ttl,
 	}
 }
 
@@ -46,14 +280,8 @@ func (db *PreparedStmtDB) Close() {
 	db.Mux.Lock()
 	defer db.Mux.Unlock()
 
-	for _, stmt := range db.Stmts {
-		go func(s *Stmt) {
-			// make sure the stmt must finish preparation first
-			<-s.prepared
-			if s.Stmt != nil {
-				_ = s.Close()
-			}
-		}(stmt)
+	if db.Stmts != nil {
+		db.Stmts.closeAll()
 	}
 	// setting db.Stmts to nil to avoid further using
 	db.Stmts = nil
@@ -63,53 +291,56 @@ func (sdb *PreparedStmtDB) Reset() {
 	sdb.Mux.Lock()
 	defer sdb.Mux.Unlock()
 
-	for _, stmt := range sdb.Stmts {
-		go func(s *Stmt) {
-			// make sure the stmt must finish preparation first
-			<-s.prepared
-			if s.Stmt != nil {
-				_ = s.Close()
-			}
-		}(stmt)
+	if sdb.Stmts != nil {
+		sdb.Stmts.closeAll()
+	} else {
+		sdb.Stmts = NewStmtCache(sdb.maxSize, sdb.ttl)
 	}
-	sdb.Stmts = make(map[string]*Stmt)
 }
 
-func (db *PreparedStmtDB) prepare(ctx context.Context, conn ConnPool, isTransaction bool, query string) (Stmt, error) {
+// prepare returns the cached statement of query or prepares it, the statement is acquired and has to be released
+// after used, so it's not closed while in use if evicted
+func (db *PreparedStmtDB) prepare(ctx context.Context, conn ConnPool, isTransaction bool, query string) (*Stmt, error) {
 	db.Mux.RLock()
-	if stmt, ok := db.Stmts[query]; ok && (!stmt.Transaction || isTransaction) {
+	if db.Stmts == nil {
 		db.Mux.RUnlock()
-		// wait for other goroutines prepared
-		<-stmt.prepared
-		if stmt.prepareErr != nil {
-			return Stmt{}, stmt.prepareErr
-		}
+		return nil, ErrInvalidDB
+	}
 
-		return *stmt, nil
+	stmt, ok := db.Stmts.get(query, true)
+	if ok {
+		atomic.AddInt64(&db.Stmts.hits, 1)
+	} else {
+		atomic.AddInt64(&db.Stmts.misses, 1)
 	}
 	db.Mux.RUnlock()
 
-	db.Mux.Lock()
-	// double check
-	if stmt, ok := db.Stmts[query]; ok && (!stmt.Transaction || isTransaction) {
-		db.Mux.Unlock()
-		// wait for other goroutines prepared
-		<-stmt.prepared
-		if stmt.prepareErr != nil {
-			return Stmt{}, stmt.prepareErr
+	if ok {
+		if !stmt.Transaction || isTransaction {
+			return waitPrepared(stmt)
 		}
-
-		return *stmt, nil
+		stmt.release()
 	}
-	// check db.Stmts first to avoid Segmentation Fault(setting value to nil map)
-	// which cause by calling Close and executing SQL concurrently
+
+	db.Mux.Lock()
+	// check db.Stmts first to avoid using a closed PreparedStmtDB, which is caused by calling Close
+	// and executing SQL concurrently
 	if db.Stmts == nil {
 		db.Mux.Unlock()
-		return Stmt{}, ErrInvalidDB
+		return nil, ErrInvalidDB
 	}
-	// cache preparing stmt first
-	cacheStmt := Stmt{Transaction: isTransaction, prepared: make(chan struct{})}
-	db.Stmts[query] = &cacheStmt
+
+	// double check
+	if stmt, ok := db.Stmts.get(query, true); ok {
+		if !stmt.Transaction || isTransaction {
+			db.Mux.Unlock()
+			return waitPrepared(stmt)
+		}
+		stmt.release()
+	}
+	// cache preparing stmt first, which is acquired by the preparing goroutine
+	cacheStmt := &Stmt{Transaction: isTransaction, prepared: make(chan struct{}), refs: 1}
+	db.Stmts.Set(query, cacheStmt)
 	db.Mux.Unlock()
 
 	// prepare completed
@@ -120,22 +351,42 @@ func (db *PreparedStmtDB) prepare(ctx context.Context, conn ConnPool, isTransact
 	// 1. g1 begin tx, g1 is requeue because of waiting for the system call, now `db.ConnPool` db.numOpen == 1.
 	// 2. g2 select lock `conn.PrepareContext(ctx, query)`, now db.numOpen == db.maxOpen , wait for release.
 	// 3. g1 tx exec insert, wait for unlock `conn.PrepareContext(ctx, query)` to finish tx and release.
-	stmt, err := conn.PrepareContext(ctx, query)
+	sqlStmt, err := conn.PrepareContext(ctx, query)
 	if err != nil {
 		cacheStmt.prepareErr = err
 		db.Mux.Lock()
-		delete(db.Stmts, query)
+		db.Stmts.delete(query, cacheStmt)
 		db.Mux.Unlock()
-		return Stmt{}, err
+		cacheStmt.release()
+		return nil, err
 	}
 
 	db.Mux.Lock()
-	cacheStmt.Stmt = stmt
+	cacheStmt.Stmt = sqlStmt
 	db.Mux.Unlock()
 
 	return cacheStmt, nil
 }
 
+// waitPrepared waits for other goroutines preparing the acquired stmt
+func waitPrepared(stmt *Stmt) (*Stmt, error) {
+	<-stmt.prepared
+	if stmt.prepareErr != nil {
+		stmt.release()
+		return nil, stmt.prepareErr
+	}
+	return stmt, nil
+}
+
+// discard removes the statement of query if the connection is bad
+func (db *PreparedStmtDB) discard(query string, stmt *Stmt, err error) {
+	if errors.Is(err, driver.ErrBadConn) {
+		db.Mux.Lock()
+		defer db.Mux.Unlock()
+		db.Stmts.delete(query, stmt)
+	}
+}
+
 func (db *PreparedStmtDB) BeginTx(ctx context.Context, opt *sql.TxOptions) (ConnPool, error) {
 	if beginner, ok := db.ConnPool.(TxBeginner); ok {
 		tx, err := beginner.BeginTx(ctx, opt)
@@ -160,13 +411,9 @@ func (db *PreparedStmtDB) BeginTx(ctx context.Context, opt *sql.TxOptions) (Conn
 func (db *PreparedStmtDB) ExecContext(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
 	stmt, err := db.prepare(ctx, db.ConnPool, false, query)
 	if err == nil {
+		defer stmt.release()
 		result, err = stmt.ExecContext(ctx, args...)
-		if errors.Is(err, driver.ErrBadConn) {
-			db.Mux.Lock()
-			defer db.Mux.Unlock()
-			go stmt.Close()
-			delete(db.Stmts, query)
-		}
+		db.discard(query, stmt, err)
 	}
 	return result, err
 }
@@ -174,14 +421,9 @@ func (db *PreparedStmtDB) ExecContext(ctx context.Context, query string, args ..
 func (db *PreparedStmtDB) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
 	stmt, err := db.prepare(ctx, db.ConnPool, false, query)
 	if err == nil {
+		defer stmt.release()
 		rows, err = stmt.QueryContext(ctx, args...)
-		if errors.Is(err, driver.ErrBadConn) {
-			db.Mux.Lock()
-			defer db.Mux.Unlock()
-
-			go stmt.Close()
-			delete(db.Stmts, query)
-		}
+		db.discard(query, stmt, err)
 	}
 	return rows, err
 }
@@ -189,6 +431,7 @@ func (db *PreparedStmtDB) QueryContext(ctx context.Context, query string, args .
 func (db *PreparedStmtDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
 	stmt, err := db.prepare(ctx, db.ConnPool, false, query)
 	if err == nil {
+		defer stmt.release()
 		return stmt.QueryRowContext(ctx, args...)
 	}
 	return &sql.Row{}
@@ -228,14 +471,9 @@ func (tx *PreparedStmtTX) Rollback() error {
 func (tx *PreparedStmtTX) ExecContext(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
 	stmt, err := tx.PreparedStmtDB.prepare(ctx, tx.Tx, true, query)
 	if err == nil {
+		defer stmt.release()
 		result, err = tx.Tx.StmtContext(ctx, stmt.Stmt).ExecContext(ctx, args...)
-		if errors.Is(err, driver.ErrBadConn) {
-			tx.PreparedStmtDB.Mux.Lock()
-			defer tx.PreparedStmtDB.Mux.Unlock()
-
-			go stmt.Close()
-			delete(tx.PreparedStmtDB.Stmts, query)
-		}
+		tx.PreparedStmtDB.discard(query, stmt, err)
 	}
 	return result, err
 }
@@ -243,14 +481,9 @@ func (tx *PreparedStmtTX) ExecContext(ctx context.Context, query string, args ..
 func (tx *PreparedStmtTX) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
 	stmt, err := tx.PreparedStmtDB.prepare(ctx, tx.Tx, true, query)
 	if err == nil {
+		defer stmt.release()
 		rows, err = tx.Tx.StmtContext(ctx, stmt.Stmt).QueryContext(ctx, args...)
-		if errors.Is(err, driver.ErrBadConn) {
-			tx.PreparedStmtDB.Mux.Lock()
-			defer tx.PreparedStmtDB.Mux.Unlock()
-
-			go stmt.Close()
-			delete(tx.PreparedStmtDB.Stmts, query)
-		}
+		tx.PreparedStmtDB.discard(query, stmt, err)
 	}
 	return rows, err
 }
@@ -258,6 +491,7 @@ func (tx *PreparedStmtTX) QueryContext(ctx context.Context, query string, args .
 func (tx *PreparedStmtTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
 	stmt, err := tx.PreparedStmtDB.prepare(ctx, tx.Tx, true, query)
 	if err == nil {
+		defer stmt.release()
 		return tx.Tx.StmtContext(ctx, stmt.Stmt).QueryRowContext(ctx, args...)
 	}
 	return &sql.Row{}
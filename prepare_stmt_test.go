@@ -0,0 +1,163 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStmtCache(t *testing.T) {
+	newStmt := func() *Stmt {
+		stmt := &Stmt{prepared: make(chan struct{})}
+		close(stmt.prepared)
+		return stmt
+	}
+
+	cache := NewStmtCache(2, 0)
+	cache.Set("a", newStmt())
+	cache.Set("b", newStmt())
+	if _, ok := cache.Get("a"); !ok {
+		t.Errorf("a should be cached")
+	}
+
+	cache.Set("c", newStmt())
+	if keys := cache.Keys(); !reflect.DeepEqual(keys, []string{"c", "a"}) {
+		t.Errorf("least recently used b should be evicted, got %v", keys)
+	}
+
+	if _, ok := cache.Get("b"); ok {
+		t.Errorf("b should be evicted")
+	}
+
+	if stats := cache.Stats(); stats != (StmtCacheStats{Hits: 1, Misses: 1, Evictions: 1}) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	cache = NewStmtCache(0, time.Millisecond)
+	cache.Set("a", newStmt())
+	time.Sleep(2 * time.Millisecond)
+	if _, ok := cache.Get("a"); ok || cache.Len() != 0 {
+		t.Errorf("a should be expired")
+	}
+
+	cache.Set("b", newStmt())
+	cache.Delete("b")
+	if stats := cache.Stats(); cache.Len() != 0 || stats != (StmtCacheStats{Misses: 1, Evictions: 1}) {
+		t.Errorf("deleted statements shouldn't be counted as evictions, got %+v", stats)
+	}
+
+	// statements used within the TTL are kept
+	cache = NewStmtCache(-1, 50*time.Millisecond)
+	cache.Set("a", newStmt())
+	cache.Set("b", newStmt())
+	for i := 0; i < 3; i++ {
+		time.Sleep(20 * time.Millisecond)
+		if _, ok := cache.Get("a"); !ok {
+			t.Fatalf("a should be kept as it's used")
+		}
+	}
+
+	cache.Set("c", newStmt())
+	if keys := cache.Keys(); !reflect.DeepEqual(keys, []string{"c", "a"}) {
+		t.Errorf("expired b should be evicted, got %v", keys)
+	}
+
+	if cache.maxSize >= 0 || NewStmtCache(0, 0).maxSize != DefaultStmtCacheSize {
+		t.Errorf("statements should be bounded by DefaultStmtCacheSize unless the size is negative")
+	}
+}
+
+// stmtDriver sql driver of statements recording executed and closed queries
+type stmtDriver struct {
+	mu     sync.Mutex
+	execs  []string
+	closed []string
+}
+
+type stmtConn struct{ driver *stmtDriver }
+
+type driverStmt struct {
+	conn  stmtConn
+	query string
+}
+
+func (d *stmtDriver) Open(string) (driver.Conn, error) { return stmtConn{d}, nil }
+
+func (c stmtConn) Prepare(query string) (driver.Stmt, error) { return &driverStmt{c, query}, nil }
+func (c stmtConn) Close() error                              { return nil }
+func (c stmtConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+func (s *driverStmt) Close() error {
+	s.conn.driver.mu.Lock()
+	defer s.conn.driver.mu.Unlock()
+	s.conn.driver.closed = append(s.conn.driver.closed, s.query)
+	return nil
+}
+
+func (s *driverStmt) NumInput() int { return -1 }
+
+func (s *driverStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.conn.driver.mu.Lock()
+	defer s.conn.driver.mu.Unlock()
+	s.conn.driver.execs = append(s.conn.driver.execs, s.query)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *driverStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestPreparedStmtEviction(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		d     = &stmtDriver{}
+		sqlDB = sql.OpenDB(stmtConnector{d})
+		db    = newPreparedStmtDB(sqlDB, 1, time.Hour)
+	)
+
+	stmt, err := db.prepare(ctx, sqlDB, false, "a")
+	if err != nil {
+		t.Fatalf("failed to prepare, got %v", err)
+	}
+
+	// evicts a while it's in use
+	if _, err := db.ExecContext(ctx, "b"); err != nil {
+		t.Fatalf("failed to exec, got %v", err)
+	}
+	if keys := db.Stmts.Keys(); !reflect.DeepEqual(keys, []string{"b"}) {
+		t.Fatalf("a should be evicted, got %v", keys)
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		t.Fatalf("evicted statement in use shouldn't be closed, got %v", err)
+	}
+	stmt.release()
+
+	for i := 0; i < 100; i++ {
+		d.mu.Lock()
+		closed := append([]string(nil), d.closed...)
+		d.mu.Unlock()
+		if reflect.DeepEqual(closed, []string{"a"}) {
+			break
+		} else if i == 99 {
+			t.Errorf("evicted statement should be closed after released, got %v", closed)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	db.Close()
+	db.Reset()
+	if db.Stmts.maxSize != 1 || db.Stmts.ttl != time.Hour {
+		t.Errorf("limits should be kept after reset, got %v, %v", db.Stmts.maxSize, db.Stmts.ttl)
+	}
+}
+
+type stmtConnector struct{ driver *stmtDriver }
+
+func (c stmtConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open("") }
+func (c stmtConnector) Driver() driver.Driver                        { return c.driver }
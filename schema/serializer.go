@@ -34,6 +34,8 @@ func init() {
 	RegisterSerializer("json", JSONSerializer{})
 	RegisterSerializer("unixtime", UnixSecondSerializer{})
 	RegisterSerializer("gob", GobSerializer{})
+	RegisterSerializer("encrypted", EncryptedSerializer{})
+	RegisterSerializer("blindindex", BlindIndexSerializer{})
 }
 
 // Serializer field value serializer
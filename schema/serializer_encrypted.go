@@ -0,0 +1,351 @@
+package schema
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrMissingKeyProvider encrypted serializers used without a registered KeyProvider
+	ErrMissingKeyProvider = errors.New("missing key provider")
+	// ErrKeyNotFound encryption key not found
+	ErrKeyNotFound = errors.New("encryption key not found")
+	// ErrInvalidEnvelope encrypted value in an unknown format
+	ErrInvalidEnvelope = errors.New("invalid encrypted envelope")
+)
+
+// encryptedAESGCMPrefix prefix of envelopes encrypted by EncryptedSerializer, envelopes are formatted
+// as `enc:aesgcm:<key id>:<base64 of nonce and ciphertext>`
+const encryptedAESGCMPrefix = "enc:aesgcm:"
+
+// KeyProvider provides keys of encrypted serializers, keys are AES keys of 16, 24 or 32 bytes
+type KeyProvider interface {
+	// ActiveKey returns the key and its ID to encrypt values
+	ActiveKey(ctx context.Context) (keyID string, key []byte, err error)
+	// Key returns the key of keyID to decrypt values encrypted with it
+	Key(ctx context.Context, keyID string) ([]byte, error)
+}
+
+// BlindIndexKeyProvider key provider of blind indexes, the key can't be rotated without recomputing indexes
+type BlindIndexKeyProvider interface {
+	BlindIndexKey(ctx context.Context) ([]byte, error)
+}
+
+var keyProvider struct {
+	sync.RWMutex
+	provider KeyProvider
+}
+
+// RegisterKeyProvider register key provider of encrypted serializers
+func RegisterKeyProvider(provider KeyProvider) {
+	keyProvider.Lock()
+	defer keyProvider.Unlock()
+	keyProvider.provider = provider
+}
+
+func getKeyProvider() (KeyProvider, error) {
+	keyProvider.RLock()
+	defer keyProvider.RUnlock()
+	if keyProvider.provider == nil {
+		return nil, ErrMissingKeyProvider
+	}
+	return keyProvider.provider, nil
+}
+
+// StaticKeyProvider key provider of fixed keys, new values are encrypted with the key of ActiveKeyID
+type StaticKeyProvider struct {
+	ActiveKeyID string
+	Keys        map[string][]byte
+	// IndexKey key of blind indexes
+	IndexKey []byte
+}
+
+// ActiveKey implements KeyProvider interface
+func (p StaticKeyProvider) ActiveKey(ctx context.Context) (string, []byte, error) {
+	key, err := p.Key(ctx, p.ActiveKeyID)
+	return p.ActiveKeyID, key, err
+}
+
+// Key implements KeyProvider interface
+func (p StaticKeyProvider) Key(ctx context.Context, keyID string) ([]byte, error) {
+	if key, ok := p.Keys[keyID]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+}
+
+// BlindIndexKey implements BlindIndexKeyProvider interface
+func (p StaticKeyProvider) BlindIndexKey(context.Context) ([]byte, error) {
+	if len(p.IndexKey) == 0 {
+		return nil, fmt.Errorf("%w: blind index key", ErrKeyNotFound)
+	}
+	return p.IndexKey, nil
+}
+
+// EncryptedSerializer AES-GCM encrypted serializer, values are always encrypted with the active key of
+// the registered KeyProvider, values encrypted with previous keys are decrypted with their key IDs and
+// re-encrypted with the active key when saved again. string and []byte fields are encrypted as is,
+// values of other types are encrypted as JSON.
+//
+// Ciphertexts are bound to the table and column of the field as additional authenticated data, so values copied
+// to other columns or tables fail to decrypt, renaming them requires decrypting and encrypting values again.
+type EncryptedSerializer struct{}
+
+// Scan implements serializer interface
+func (EncryptedSerializer) Scan(ctx context.Context, field *Field, dst reflect.Value, dbValue interface{}) (err error) {
+	fieldValue := reflect.New(field.FieldType)
+
+	if dbValue != nil {
+		var envelope string
+		switch v := dbValue.(type) {
+		case []byte:
+			envelope = string(v)
+		case string:
+			envelope = v
+		default:
+			return fmt.Errorf("failed to decrypt value: %#v", dbValue)
+		}
+
+		if envelope != "" {
+			plaintext, err := decryptEnvelope(ctx, envelope, additionalData(field))
+			if err != nil {
+				return err
+			}
+
+			if err := setPlaintext(fieldValue.Elem(), plaintext); err != nil {
+				return err
+			}
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return
+}
+
+// Value implements serializer interface
+func (EncryptedSerializer) Value(ctx context.Context, field *Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, ok, err := plaintextOf(fieldValue)
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	provider, err := getKeyProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	keyID, key, err := provider.ActiveKey(ctx)
+	if err != nil {
+		return nil, err
+	} else if strings.Contains(keyID, ":") {
+		return nil, fmt.Errorf("invalid key id %q, key ids can't contain colons", keyID)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, additionalData(field))
+	return encryptedAESGCMPrefix + keyID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// additionalData additional authenticated data of the field, which is its table and column
+func additionalData(field *Field) []byte {
+	var table string
+	if field.Schema != nil {
+		table = field.Schema.Table
+	}
+	return []byte(table + "." + field.DBName)
+}
+
+func decryptEnvelope(ctx context.Context, envelope string, aad []byte) ([]byte, error) {
+	if !strings.HasPrefix(envelope, encryptedAESGCMPrefix) {
+		return nil, ErrInvalidEnvelope
+	}
+
+	keyID, encoded, ok := strings.Cut(strings.TrimPrefix(envelope, encryptedAESGCMPrefix), ":")
+	if !ok {
+		return nil, ErrInvalidEnvelope
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+
+	provider, err := getKeyProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := provider.Key(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrInvalidEnvelope
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// plaintextOf returns plaintext of the field value, ok is false for nil values
+func plaintextOf(fieldValue interface{}) (plaintext []byte, ok bool, err error) {
+	rv := reflect.ValueOf(fieldValue)
+	if !rv.IsValid() || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		return nil, false, nil
+	}
+
+	rv = reflect.Indirect(rv)
+	switch {
+	case rv.Kind() == reflect.String:
+		return []byte(rv.String()), true, nil
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		if rv.IsNil() {
+			return nil, false, nil
+		}
+		return rv.Bytes(), true, nil
+	default:
+		plaintext, err = json.Marshal(rv.Interface())
+		return plaintext, true, err
+	}
+}
+
+func setPlaintext(rv reflect.Value, plaintext []byte) error {
+	if rv.Kind() == reflect.Ptr {
+		rv.Set(reflect.New(rv.Type().Elem()))
+		rv = rv.Elem()
+	}
+
+	switch {
+	case rv.Kind() == reflect.String:
+		rv.SetString(string(plaintext))
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		rv.SetBytes(plaintext)
+	default:
+		return json.Unmarshal(plaintext, rv.Addr().Interface())
+	}
+	return nil
+}
+
+// BlindIndexSerializer blind index of an encrypted field for equality lookups, the index is the HMAC-SHA256
+// of the plaintext with the blind index key of the registered KeyProvider, which has to implement
+// BlindIndexKeyProvider, the indexed field is specified by the blindIndex tag, the index is assigned whenever the
+// indexed field is updated
+//
+//	type User struct {
+//		Phone      string `gorm:"serializer:encrypted"`
+//		PhoneIndex string `gorm:"serializer:blindindex;blindIndex:Phone;index"`
+//	}
+//
+//	index, _ := schema.BlindIndex(ctx, "+1 555 0100")
+//	db.Where("phone_index = ?", index).First(&user)
+type BlindIndexSerializer struct{}
+
+// Scan implements serializer interface
+func (BlindIndexSerializer) Scan(ctx context.Context, field *Field, dst reflect.Value, dbValue interface{}) (err error) {
+	fieldValue := reflect.New(field.FieldType)
+
+	switch v := dbValue.(type) {
+	case []byte:
+		err = setPlaintext(fieldValue.Elem(), v)
+	case string:
+		err = setPlaintext(fieldValue.Elem(), []byte(v))
+	case nil:
+	default:
+		return fmt.Errorf("failed to scan blind index: %#v", dbValue)
+	}
+
+	if err == nil {
+		field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	}
+	return
+}
+
+// Value implements serializer interface
+func (BlindIndexSerializer) Value(ctx context.Context, field *Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	source := field.Schema.LookUpField(field.TagSettings["BLINDINDEX"])
+	if source == nil {
+		return nil, fmt.Errorf("blind index field %s of %s not found", field.TagSettings["BLINDINDEX"], field.Name)
+	}
+
+	index, ok, err := blindIndex(ctx, source.ReflectValueOf(ctx, dst).Interface())
+	if !ok || err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// BlindIndexFieldsOf returns blind index fields indexing field, which are assigned whenever field is updated
+func (schema *Schema) BlindIndexFieldsOf(field *Field) (fields []*Field) {
+	for _, f := range schema.Fields {
+		if name, ok := f.TagSettings["BLINDINDEX"]; ok && f.DBName != "" && schema.LookUpField(name) == field {
+			fields = append(fields, f)
+		}
+	}
+	return
+}
+
+// BlindIndex returns the blind index of value, which is used to query fields of BlindIndexSerializer
+func BlindIndex(ctx context.Context, value interface{}) (string, error) {
+	index, _, err := blindIndex(ctx, value)
+	return index, err
+}
+
+func blindIndex(ctx context.Context, value interface{}) (string, bool, error) {
+	plaintext, ok, err := plaintextOf(value)
+	if !ok || err != nil {
+		return "", ok, err
+	}
+
+	provider, err := getKeyProvider()
+	if err != nil {
+		return "", false, err
+	}
+
+	indexKeyProvider, ok := provider.(BlindIndexKeyProvider)
+	if !ok {
+		return "", false, fmt.Errorf("%w: key provider %T doesn't provide blind index key", ErrKeyNotFound, provider)
+	}
+
+	key, err := indexKeyProvider.BlindIndexKey(ctx)
+	if err != nil {
+		return "", false, err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(plaintext)
+	return hex.EncodeToString(mac.Sum(nil)), true, nil
+}
@@ -0,0 +1,146 @@
+package schema_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"gorm.io/gorm/utils/tests"
+)
+
+type encryptedUser struct {
+	ID         uint
+	Phone      string            `gorm:"serializer:encrypted"`
+	PhoneIndex string            `gorm:"serializer:blindindex;blindIndex:Phone"`
+	Profile    map[string]string `gorm:"serializer:encrypted"`
+}
+
+type encryptedAccount struct {
+	ID    uint
+	Phone string `gorm:"serializer:encrypted"`
+}
+
+func TestEncryptedSerializer(t *testing.T) {
+	defer schema.RegisterKeyProvider(nil)
+
+	var (
+		ctx       = context.Background()
+		s, err    = schema.Parse(&encryptedUser{}, &sync.Map{}, schema.NamingStrategy{})
+		key1      = []byte("0123456789abcdef")
+		key2      = []byte("fedcba9876543210fedcba9876543210")
+		user      = encryptedUser{Phone: "+1 555 0100", Profile: map[string]string{"national_id": "A123"}}
+		userValue = reflect.ValueOf(&user).Elem()
+		valueOf   = func(name string) interface{} {
+			value, _ := s.LookUpField(name).ValueOf(ctx, userValue)
+			v, err := value.(driver.Valuer).Value()
+			if err != nil {
+				t.Fatalf("failed to get value of %v, got %v", name, err)
+			}
+			return v
+		}
+	)
+	if err != nil {
+		t.Fatalf("failed to parse schema, got %v", err)
+	}
+
+	if _, err := s.LookUpField("Phone").Serializer.Value(ctx, s.LookUpField("Phone"), userValue, user.Phone); !errors.Is(err, schema.ErrMissingKeyProvider) {
+		t.Errorf("encrypting without key provider should fail, got %v", err)
+	}
+
+	schema.RegisterKeyProvider(schema.StaticKeyProvider{ActiveKeyID: "k1", Keys: map[string][]byte{"k1": key1}, IndexKey: []byte("index")})
+	phone := valueOf("Phone").(string)
+	if !strings.HasPrefix(phone, "enc:aesgcm:k1:") || strings.Contains(phone, user.Phone) {
+		t.Fatalf("phone should be encrypted with k1, got %v", phone)
+	}
+	profile := valueOf("Profile")
+
+	index, err := schema.BlindIndex(ctx, user.Phone)
+	if err != nil || valueOf("PhoneIndex") != index {
+		t.Errorf("blind index should be computed from phone, expects %v, got %v, err %v", index, valueOf("PhoneIndex"), err)
+	}
+
+	// rotate to k2, values encrypted with k1 are still decrypted
+	schema.RegisterKeyProvider(schema.StaticKeyProvider{ActiveKeyID: "k2", Keys: map[string][]byte{"k1": key1, "k2": key2}, IndexKey: []byte("index")})
+
+	var result encryptedUser
+	resultValue := reflect.ValueOf(&result).Elem()
+	for name, dbValue := range map[string]interface{}{"Phone": []byte(phone), "Profile": profile, "PhoneIndex": index} {
+		field := s.LookUpField(name)
+		if err := field.Serializer.Scan(ctx, field, resultValue, dbValue); err != nil {
+			t.Fatalf("failed to scan %v, got %v", name, err)
+		}
+	}
+
+	if result.Phone != user.Phone || !reflect.DeepEqual(result.Profile, user.Profile) || result.PhoneIndex != index {
+		t.Errorf("decrypted user should be %+v, got %+v", user, result)
+	}
+
+	if phone := valueOf("Phone").(string); !strings.HasPrefix(phone, "enc:aesgcm:k2:") {
+		t.Errorf("phone should be re-encrypted with k2, got %v", phone)
+	}
+
+	// ciphertexts are bound to their columns
+	if err := s.LookUpField("Profile").Serializer.Scan(ctx, s.LookUpField("Profile"), resultValue, phone); err == nil {
+		t.Errorf("decrypting values of other columns should fail")
+	}
+
+	other, err := schema.Parse(&encryptedAccount{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got %v", err)
+	}
+
+	otherValue := reflect.New(other.ModelType).Elem()
+	if err := other.LookUpField("Phone").Serializer.Scan(ctx, other.LookUpField("Phone"), otherValue, phone); err == nil {
+		t.Errorf("decrypting values of other tables should fail")
+	}
+
+	schema.RegisterKeyProvider(schema.StaticKeyProvider{ActiveKeyID: "k2", Keys: map[string][]byte{"k2": key2}})
+	field := s.LookUpField("Phone")
+	if err := field.Serializer.Scan(ctx, field, resultValue, phone); !errors.Is(err, schema.ErrKeyNotFound) {
+		t.Errorf("decrypting with removed key should fail, got %v", err)
+	}
+
+	if err := field.Serializer.Scan(ctx, field, resultValue, "plain"); !errors.Is(err, schema.ErrInvalidEnvelope) {
+		t.Errorf("decrypting unknown format should fail, got %v", err)
+	}
+}
+
+func TestEncryptedSerializerUpdate(t *testing.T) {
+	defer schema.RegisterKeyProvider(nil)
+	schema.RegisterKeyProvider(schema.StaticKeyProvider{ActiveKeyID: "k1", Keys: map[string][]byte{"k1": []byte("0123456789abcdef")}, IndexKey: []byte("index")})
+
+	var (
+		db, _    = gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, SkipDefaultTransaction: true})
+		index, _ = schema.BlindIndex(context.Background(), "+1 555 0100")
+	)
+
+	for idx, tx := range []*gorm.DB{
+		db.Model(&encryptedUser{ID: 1}).Update("phone", "+1 555 0100"),
+		db.Model(&encryptedUser{ID: 1}).Updates(map[string]interface{}{"Phone": "+1 555 0100"}),
+		db.Model(&encryptedUser{ID: 1}).Updates(encryptedUser{Phone: "+1 555 0100"}),
+	} {
+		if tx.Error != nil {
+			t.Fatalf("#%d failed to update, got %v", idx, tx.Error)
+		}
+		tests.AssertEqual(t, tx.Statement.SQL.String(), "UPDATE `encrypted_users` SET `phone`=?,`phone_index`=? WHERE `id` = ?")
+
+		valuer, ok := tx.Statement.Vars[0].(driver.Valuer)
+		if !ok {
+			t.Fatalf("#%d phone should be encrypted, got %#v", idx, tx.Statement.Vars[0])
+		}
+		if phone, err := valuer.Value(); err != nil || !strings.HasPrefix(phone.(string), "enc:aesgcm:k1:") {
+			t.Errorf("#%d phone should be encrypted with k1, got %v, err %v", idx, phone, err)
+		}
+		tests.AssertEqual(t, tx.Statement.Vars[1], index)
+	}
+
+	if err := db.Model(&encryptedUser{ID: 1}).Update("profile", `{"national_id":"A123"}`).Error; err == nil {
+		t.Errorf("updating encrypted fields with values of other types should fail")
+	}
+}
@@ -1,10 +1,14 @@
 package gorm
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/jinzhu/now"
 	"gorm.io/gorm/clause"
@@ -74,27 +78,72 @@ func (sd SoftDeleteQueryClause) MergeClause(*clause.Clause) {
 }
 
 func (sd SoftDeleteQueryClause) ModifyStatement(stmt *Statement) {
-	if _, ok := stmt.Clauses["soft_delete_enabled"]; !ok && !stmt.Statement.Unscoped {
-		if c, ok := stmt.Clauses["WHERE"]; ok {
-			if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) >= 1 {
-				for _, expr := range where.Exprs {
-					if orCond, ok := expr.(clause.OrConditions); ok && len(orCond.Exprs) == 1 {
-						where.Exprs = []clause.Expression{clause.And(where.Exprs...)}
-						c.Expression = where
-						stmt.Clauses["WHERE"] = c
-						break
-					}
-				}
+	addSoftDeleteCondition(stmt, sd.Field, sd.ZeroValue)
+}
+
+// addSoftDeleteCondition add condition that field equals to the value of not deleted records,
+// or condition of deleted records for OnlyTrashed
+func addSoftDeleteCondition(stmt *Statement, field *schema.Field, notDeleted interface{}) {
+	var cond clause.Expression = clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: notDeleted}
+	if _, onlyTrashed := stmt.Clauses[onlyTrashedKey]; onlyTrashed {
+		if sd, ok := softDeleteFieldOf(field); ok {
+			var err error
+			if cond, err = sd.DeletedCondition(field, time.Time{}); err != nil {
+				stmt.AddError(err)
+				return
 			}
 		}
+	} else if stmt.Statement.Unscoped {
+		return
+	}
 
-		stmt.AddClause(clause.Where{Exprs: []clause.Expression{
-			clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: sd.Field.DBName}, Value: sd.ZeroValue},
-		}})
+	if _, ok := stmt.Clauses["soft_delete_enabled"]; !ok {
+		stmt.AddAndConditions(cond)
 		stmt.Clauses["soft_delete_enabled"] = clause.Clause{}
 	}
 }
 
+// SoftDeleteField soft delete field types, used by OnlyTrashed, Restore and Purge
+type SoftDeleteField interface {
+	// NotDeletedValue value of the field for records not deleted
+	NotDeletedValue(f *schema.Field) interface{}
+	// DeletedCondition condition of deleted records, which are deleted before deletedBefore if it is not zero
+	DeletedCondition(f *schema.Field, deletedBefore time.Time) (clause.Expression, error)
+}
+
+// onlyTrashedKey clause marking statements of OnlyTrashed, stored as a clause rather than a setting, so it isn't
+// passed to statements of preloads and associations
+const onlyTrashedKey = "gorm:only_trashed"
+
+func softDeleteFieldOf(field *schema.Field) (SoftDeleteField, bool) {
+	sd, ok := reflect.New(field.IndirectFieldType).Elem().Interface().(SoftDeleteField)
+	return sd, ok
+}
+
+// lookUpSoftDeleteField the soft delete field of schema
+func lookUpSoftDeleteField(s *schema.Schema) (*schema.Field, SoftDeleteField) {
+	for _, field := range s.Fields {
+		if field.DBName != "" {
+			if sd, ok := softDeleteFieldOf(field); ok {
+				return field, sd
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (DeletedAt) NotDeletedValue(f *schema.Field) interface{} {
+	return parseZeroValueTag(f)
+}
+
+func (DeletedAt) DeletedCondition(f *schema.Field, deletedBefore time.Time) (clause.Expression, error) {
+	column := clause.Column{Table: clause.CurrentTable, Name: f.DBName}
+	if deletedBefore.IsZero() {
+		return clause.Neq{Column: column, Value: parseZeroValueTag(f)}, nil
+	}
+	return clause.And(clause.Neq{Column: column, Value: parseZeroValueTag(f)}, clause.Lt{Column: column, Value: deletedBefore}), nil
+}
+
 func (DeletedAt) UpdateClauses(f *schema.Field) []clause.Interface {
 	return []clause.Interface{SoftDeleteUpdateClause{Field: f, ZeroValue: parseZeroValueTag(f)}}
 }
@@ -141,30 +190,277 @@ func (sd SoftDeleteDeleteClause) MergeClause(*clause.Clause) {
 
 func (sd SoftDeleteDeleteClause) ModifyStatement(stmt *Statement) {
 	if stmt.SQL.Len() == 0 && !stmt.Statement.Unscoped {
-		curTime := stmt.DB.NowFunc()
-		stmt.AddClause(clause.Set{{Column: clause.Column{Name: sd.Field.DBName}, Value: curTime}})
-		stmt.SetColumn(sd.Field.DBName, curTime, true)
+		buildSoftDelete(stmt, sd.Field, stmt.DB.NowFunc(), SoftDeleteQueryClause(sd))
+	}
+}
 
-		if stmt.Schema != nil {
-			_, queryValues := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
-			column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+type deletedByCtxKey struct{}
+
+// WithDeletedBy returns a context carrying the deleter, which is saved to the column specified by `deletedBy` tag
+// of soft delete fields when records are deleted with the context
+//
+//	type User struct {
+//		ID        uint
+//		DeletedAt gorm.DeletedAt `gorm:"deletedBy:DeletedBy"`
+//		DeletedBy string
+//	}
+//
+//	db.WithContext(gorm.WithDeletedBy(ctx, "admin")).Delete(&user)
+//	// UPDATE users SET deleted_at="2013-10-29 10:23",deleted_by="admin" WHERE id = 1 AND deleted_at IS NULL
+func WithDeletedBy(ctx context.Context, deletedBy interface{}) context.Context {
+	return context.WithValue(ctx, deletedByCtxKey{}, deletedBy)
+}
+
+// buildSoftDelete build soft delete statement that sets field to deleted
+func buildSoftDelete(stmt *Statement, field *schema.Field, deleted interface{}, queryClause StatementModifier) {
+	set := clause.Set{{Column: clause.Column{Name: field.DBName}, Value: deleted}}
+	stmt.SetColumn(field.DBName, deleted, true)
+
+	if name, ok := field.TagSettings["DELETEDBY"]; ok && stmt.Schema != nil {
+		if deletedBy := stmt.Context.Value(deletedByCtxKey{}); deletedBy != nil {
+			if byField := stmt.Schema.LookUpField(name); byField != nil && byField.DBName != "" {
+				set = append(set, clause.Assignment{Column: clause.Column{Name: byField.DBName}, Value: deletedBy})
+				stmt.SetColumn(byField.DBName, deletedBy, true)
+			} else {
+				stmt.AddError(fmt.Errorf("%w: deleted by field %s not found", ErrInvalidField, name))
+			}
+		}
+	}
+	stmt.AddClause(set)
+
+	if stmt.Schema != nil {
+		_, queryValues := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+		column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+
+		if len(values) > 0 {
+			stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+		}
+
+		if stmt.ReflectValue.CanAddr() && stmt.Dest != stmt.Model && stmt.Model != nil {
+			_, queryValues = schema.GetIdentityFieldValuesMap(stmt.Context, reflect.ValueOf(stmt.Model), stmt.Schema.PrimaryFields)
+			column, values = schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
 
 			if len(values) > 0 {
 				stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
 			}
+		}
+	}
+
+	queryClause.ModifyStatement(stmt)
+	stmt.AddClauseIfNotExists(clause.Update{})
+	stmt.Build(stmt.DB.Callback().Update().Clauses...)
+}
+
+// DeletedUnix soft delete field of unix time, 0 for records not deleted, so it could be a part of unique indexes.
+// The time unit defaults to seconds, use tag `softDelete:milli` or `softDelete:nano` for milliseconds or nanoseconds
+//
+//	type User struct {
+//		ID        uint
+//		Name      string     `gorm:"uniqueIndex:udx_name"`
+//		DeletedAt gorm.DeletedUnix `gorm:"softDelete:milli;uniqueIndex:udx_name"`
+//	}
+type DeletedUnix int64
+
+func (DeletedUnix) QueryClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{SoftDeleteFlagQueryClause{Field: f}}
+}
+
+func (DeletedUnix) UpdateClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{SoftDeleteFlagUpdateClause{Field: f}}
+}
 
-			if stmt.ReflectValue.CanAddr() && stmt.Dest != stmt.Model && stmt.Model != nil {
-				_, queryValues = schema.GetIdentityFieldValuesMap(stmt.Context, reflect.ValueOf(stmt.Model), stmt.Schema.PrimaryFields)
-				column, values = schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+func (DeletedUnix) DeleteClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{SoftDeleteFlagDeleteClause{Field: f, Deleted: func(stmt *Statement) interface{} {
+		return unixOf(f, stmt.DB.NowFunc())
+	}}}
+}
 
-				if len(values) > 0 {
-					stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
-				}
+func (DeletedUnix) NotDeletedValue(*schema.Field) interface{} {
+	return 0
+}
+
+func (DeletedUnix) DeletedCondition(f *schema.Field, deletedBefore time.Time) (clause.Expression, error) {
+	column := clause.Column{Table: clause.CurrentTable, Name: f.DBName}
+	if deletedBefore.IsZero() {
+		return clause.Neq{Column: column, Value: 0}, nil
+	}
+	return clause.And(clause.Neq{Column: column, Value: 0}, clause.Lt{Column: column, Value: unixOf(f, deletedBefore)}), nil
+}
+
+func unixOf(f *schema.Field, t time.Time) int64 {
+	switch strings.ToUpper(f.TagSettings["SOFTDELETE"]) {
+	case "MILLI":
+		return t.UnixMilli()
+	case "NANO":
+		return t.UnixNano()
+	default:
+		return t.Unix()
+	}
+}
+
+// DeletedFlag soft delete flag, 0 for records not deleted and 1 for deleted records
+type DeletedFlag uint8
+
+func (DeletedFlag) QueryClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{SoftDeleteFlagQueryClause{Field: f}}
+}
+
+func (DeletedFlag) UpdateClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{SoftDeleteFlagUpdateClause{Field: f}}
+}
+
+func (DeletedFlag) DeleteClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{SoftDeleteFlagDeleteClause{Field: f, Deleted: func(*Statement) interface{} {
+		return 1
+	}}}
+}
+
+func (DeletedFlag) NotDeletedValue(*schema.Field) interface{} {
+	return 0
+}
+
+func (DeletedFlag) DeletedCondition(f *schema.Field, deletedBefore time.Time) (clause.Expression, error) {
+	if !deletedBefore.IsZero() {
+		return nil, fmt.Errorf("%w: deletion time of flag %s is unknown", ErrInvalidField, f.Name)
+	}
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Value: 1}, nil
+}
+
+// SoftDeleteFlagQueryClause query clause of soft delete fields that use 0 for records not deleted
+type SoftDeleteFlagQueryClause struct {
+	Field *schema.Field
+}
+
+func (sd SoftDeleteFlagQueryClause) Name() string {
+	return ""
+}
+
+func (sd SoftDeleteFlagQueryClause) Build(clause.Builder) {
+}
+
+func (sd SoftDeleteFlagQueryClause) MergeClause(*clause.Clause) {
+}
+
+func (sd SoftDeleteFlagQueryClause) ModifyStatement(stmt *Statement) {
+	addSoftDeleteCondition(stmt, sd.Field, 0)
+}
+
+type SoftDeleteFlagUpdateClause struct {
+	Field *schema.Field
+}
+
+func (sd SoftDeleteFlagUpdateClause) Name() string {
+	return ""
+}
+
+func (sd SoftDeleteFlagUpdateClause) Build(clause.Builder) {
+}
+
+func (sd SoftDeleteFlagUpdateClause) MergeClause(*clause.Clause) {
+}
+
+func (sd SoftDeleteFlagUpdateClause) ModifyStatement(stmt *Statement) {
+	if stmt.SQL.Len() == 0 && !stmt.Statement.Unscoped {
+		SoftDeleteFlagQueryClause(sd).ModifyStatement(stmt)
+	}
+}
+
+// SoftDeleteFlagDeleteClause delete clause of soft delete fields that use 0 for records not deleted,
+// Deleted returns the value of deleted records
+type SoftDeleteFlagDeleteClause struct {
+	Field   *schema.Field
+	Deleted func(*Statement) interface{}
+}
+
+func (sd SoftDeleteFlagDeleteClause) Name() string {
+	return ""
+}
+
+func (sd SoftDeleteFlagDeleteClause) Build(clause.Builder) {
+}
+
+func (sd SoftDeleteFlagDeleteClause) MergeClause(*clause.Clause) {
+}
+
+func (sd SoftDeleteFlagDeleteClause) ModifyStatement(stmt *Statement) {
+	if stmt.SQL.Len() == 0 && !stmt.Statement.Unscoped {
+		buildSoftDelete(stmt, sd.Field, sd.Deleted(stmt), SoftDeleteFlagQueryClause{Field: sd.Field})
+	}
+}
+
+// purgeBatchSize number of records deleted at once by Purge
+const purgeBatchSize = 500
+
+// softDeleteCascades has one and has many relationships of s that are soft deleted
+func softDeleteCascades(s *schema.Schema) (rels []*schema.Relationship) {
+	for _, rel := range append(append([]*schema.Relationship{}, s.Relationships.HasOne...), s.Relationships.HasMany...) {
+		if field, _ := lookUpSoftDeleteField(rel.FieldSchema); field != nil {
+			rels = append(rels, rel)
+		}
+	}
+	return
+}
+
+// notReferencedConditions conditions of records of db's model not referenced by has one and has many associations
+// that aren't soft deleted, which are kept by purgeSoftDeleted as deleting them violates foreign keys
+func notReferencedConditions(db *DB) (conds []clause.Expression) {
+	s := db.Statement.Schema
+	for _, rel := range append(append([]*schema.Relationship{}, s.Relationships.HasOne...), s.Relationships.HasMany...) {
+		referencing := db.Session(&Session{NewDB: true}).Model(reflect.New(rel.FieldSchema.ModelType).Interface()).Select("1")
+		for _, ref := range rel.References {
+			column := clause.Column{Table: rel.FieldSchema.Table, Name: ref.ForeignKey.DBName}
+			if ref.OwnPrimaryKey {
+				referencing = referencing.Where(clause.Eq{Column: column, Value: clause.Column{Table: s.Table, Name: ref.PrimaryKey.DBName}})
+			} else if ref.PrimaryValue != "" {
+				referencing = referencing.Where(clause.Eq{Column: column, Value: ref.PrimaryValue})
 			}
 		}
+		conds = append(conds, clause.Expr{SQL: "NOT EXISTS (?)", Vars: []interface{}{referencing}})
+	}
+	return
+}
 
-		SoftDeleteQueryClause(sd).ModifyStatement(stmt)
-		stmt.AddClauseIfNotExists(clause.Update{})
-		stmt.Build(stmt.DB.Callback().Update().Clauses...)
+// purgeSoftDeleted permanently deletes soft deleted records of db's model in batches, and those of its associations,
+// records still referenced by associations that aren't soft deleted are kept
+func purgeSoftDeleted(db *DB, deletedBefore time.Time) (rowsAffected int64, err error) {
+	if err = db.Statement.Parse(db.Statement.Model); err != nil {
+		return
+	}
+
+	field, sd := lookUpSoftDeleteField(db.Statement.Schema)
+	if field == nil {
+		return 0, fmt.Errorf("%w: %s has no soft delete field", ErrInvalidField, db.Statement.Schema.Name)
+	}
+
+	cond, err := sd.DeletedCondition(field, deletedBefore)
+	if err != nil {
+		return
+	}
+
+	cascades := softDeleteCascades(db.Statement.Schema)
+	conds := append([]clause.Expression{cond}, notReferencedConditions(db)...)
+	for {
+		records := reflect.New(reflect.SliceOf(db.Statement.Schema.ModelType))
+		if err = db.Session(&Session{}).Unscoped().Where(clause.And(conds...)).Limit(purgeBatchSize).Find(records.Interface()).Error; err != nil || records.Elem().Len() == 0 {
+			return
+		}
+
+		for _, rel := range cascades {
+			child := reflect.New(rel.FieldSchema.ModelType).Interface()
+			conds := rel.ToQueryConditions(db.Statement.Context, records)
+			if _, err = purgeSoftDeleted(db.Session(&Session{NewDB: true}).Model(child).Where(clause.And(conds...)), time.Time{}); err != nil {
+				return
+			}
+		}
+
+		result := db.Session(&Session{NewDB: true}).Unscoped().Delete(records.Interface())
+		if err = result.Error; err != nil {
+			return
+		}
+		rowsAffected += result.RowsAffected
+
+		if records.Elem().Len() < purgeBatchSize {
+			return
+		}
 	}
 }
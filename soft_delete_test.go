@@ -0,0 +1,128 @@
+package gorm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+type unixDeletedUser struct {
+	ID        uint
+	Name      string
+	DeletedAt gorm.DeletedUnix `gorm:"softDelete:milli;deletedBy:DeletedBy"`
+	DeletedBy string
+}
+
+type flagDeletedUser struct {
+	ID        uint
+	Name      string
+	IsDeleted gorm.DeletedFlag
+}
+
+func TestSoftDeleteModes(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: recorder, NowFunc: func() time.Time { return now }})
+
+	db.Where("name = ?", "jinzhu").Find(&[]unixDeletedUser{})
+	db.Delete(&unixDeletedUser{ID: 1})
+	db.WithContext(gorm.WithDeletedBy(context.Background(), "admin")).Delete(&unixDeletedUser{ID: 1})
+	db.Model(&flagDeletedUser{}).Where("name = ?", "jinzhu").Or("id = ?", 1).Update("name", "hello")
+	db.Delete(&flagDeletedUser{ID: 1})
+	db.Unscoped().Delete(&flagDeletedUser{ID: 1})
+
+	tests.AssertEqual(t, recorder.SQLs, []string{
+		"SELECT * FROM `unix_deleted_users` WHERE name = \"jinzhu\" AND `unix_deleted_users`.`deleted_at` = 0",
+		"UPDATE `unix_deleted_users` SET `deleted_at`=1704164645000 WHERE `unix_deleted_users`.`id` = 1 AND `unix_deleted_users`.`deleted_at` = 0",
+		"UPDATE `unix_deleted_users` SET `deleted_at`=1704164645000,`deleted_by`=\"admin\" WHERE `unix_deleted_users`.`id` = 1 AND `unix_deleted_users`.`deleted_at` = 0",
+		"UPDATE `flag_deleted_users` SET `name`=\"hello\" WHERE (name = \"jinzhu\" OR id = 1) AND `flag_deleted_users`.`is_deleted` = 0",
+		"UPDATE `flag_deleted_users` SET `is_deleted`=1 WHERE `flag_deleted_users`.`id` = 1 AND `flag_deleted_users`.`is_deleted` = 0",
+		"DELETE FROM `flag_deleted_users` WHERE `flag_deleted_users`.`id` = 1",
+	})
+}
+
+type trashedAuthor struct {
+	ID        uint
+	Name      string
+	Books     []trashedBook `gorm:"foreignKey:AuthorID"`
+	DeletedAt gorm.DeletedAt
+}
+
+type trashedBook struct {
+	ID        uint
+	AuthorID  uint
+	DeletedAt gorm.DeletedUnix
+}
+
+func TestRestoreAndPurge(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: recorder, NowFunc: func() time.Time { return now }})
+
+	db.OnlyTrashed().Where("name = ?", "jinzhu").Find(&[]trashedAuthor{})
+	db.Restore(&trashedAuthor{ID: 1})
+	db.Select("Books").Restore(&trashedAuthor{ID: 1})
+	db.Restore(&trashedBook{}, "author_id = ?", 2)
+	db.Restore(&unixDeletedUser{ID: 1})
+	db.Model(&trashedAuthor{}).Purge(24 * time.Hour)
+
+	tests.AssertEqual(t, recorder.SQLs, []string{
+		"SELECT * FROM `trashed_authors` WHERE name = \"jinzhu\" AND `trashed_authors`.`deleted_at` IS NOT NULL",
+		"UPDATE `trashed_authors` SET `deleted_at`=NULL WHERE `trashed_authors`.`deleted_at` IS NOT NULL AND `id` = 1",
+		"UPDATE `trashed_authors` SET `deleted_at`=NULL WHERE `trashed_authors`.`deleted_at` IS NOT NULL AND `id` = 1",
+		"UPDATE `trashed_books` SET `deleted_at`=0 WHERE `trashed_books`.`author_id` = 1 AND `trashed_books`.`deleted_at` <> 0",
+		"UPDATE `trashed_books` SET `deleted_at`=0 WHERE author_id = 2 AND `trashed_books`.`deleted_at` <> 0",
+		"UPDATE `unix_deleted_users` SET `deleted_at`=0,`deleted_by`=\"\" WHERE `unix_deleted_users`.`deleted_at` <> 0 AND `id` = 1",
+		"SELECT * FROM `trashed_authors` WHERE (`trashed_authors`.`deleted_at` IS NOT NULL AND `trashed_authors`.`deleted_at` < \"2024-01-01 03:04:05\") AND NOT EXISTS (SELECT 1 FROM `trashed_books` WHERE `trashed_books`.`author_id` = `trashed_authors`.`id` AND `trashed_books`.`deleted_at` = 0) LIMIT 500",
+	})
+
+	if err := db.OnlyTrashed().Find(&[]tests.Language{}).Error; !errors.Is(err, gorm.ErrInvalidField) {
+		t.Errorf("only trashed of model without soft delete field should fail, got %v", err)
+	}
+
+	if err := db.OnlyTrashed().Table("users").Find(&[]map[string]interface{}{}).Error; !errors.Is(err, gorm.ErrModelValueRequired) {
+		t.Errorf("only trashed without model should fail, got %v", err)
+	}
+
+	if err := db.Restore(&tests.Language{}).Error; !errors.Is(err, gorm.ErrInvalidField) {
+		t.Errorf("restore of model without soft delete field should fail, got %v", err)
+	}
+
+	if err := db.Restore(&tests.Pet{}).Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("restore without conditions should fail, got %v", err)
+	}
+
+	if err := db.Model(&flagDeletedUser{}).Purge(time.Hour).Error; !errors.Is(err, gorm.ErrInvalidField) {
+		t.Errorf("purge of flags by deletion time should fail, got %v", err)
+	}
+}
+
+func TestOnlyTrashedPreload(t *testing.T) {
+	db, _ := gorm.Open(tests.DummyDialector{}, nil)
+
+	// stub database access, queries of authors return a deleted author
+	var sqls []string
+	db.Callback().Query().Replace("gorm:query", func(db *gorm.DB) {
+		callbacks.BuildQuerySQL(db)
+		sqls = append(sqls, db.Statement.SQL.String())
+		if authors, ok := db.Statement.Dest.(*[]trashedAuthor); ok {
+			*authors = []trashedAuthor{{ID: 1, DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}}
+			db.RowsAffected = 1
+		}
+	})
+
+	if err := db.OnlyTrashed().Preload("Books").Find(&[]trashedAuthor{}).Error; err != nil {
+		t.Fatalf("failed to find trashed authors, got %v", err)
+	}
+
+	tests.AssertEqual(t, sqls, []string{
+		"SELECT * FROM `trashed_authors` WHERE `trashed_authors`.`deleted_at` IS NOT NULL",
+		"SELECT * FROM `trashed_books` WHERE `trashed_books`.`author_id` = ? AND `trashed_books`.`deleted_at` = ?",
+	})
+}
@@ -166,6 +166,36 @@ func (stmt *Statement) Quote(field interface{}) string {
 	return builder.String()
 }
 
+var collationRegexp = regexp.MustCompile(`^"?[\w\-.@]+"?$`)
+
+// Collate returns collation aware expression of column, the Dialector could customize it by implementing CollateDialector,
+// ErrUnsupportedDriver is reported for collations the Dialector doesn't support instead of ignoring them
+func (stmt *Statement) Collate(column clause.Column, collation string) clause.Expression {
+	if collator, ok := stmt.DB.Dialector.(CollateDialector); ok {
+		return collator.Collate(column, collation)
+	}
+
+	if collation == clause.CollationGBK {
+		switch stmt.DB.Dialector.Name() {
+		case "mysql":
+			return clause.Expr{SQL: "CONVERT(? USING gbk)", Vars: []interface{}{column}}
+		case "postgres":
+			return clause.Expr{SQL: `? COLLATE "zh-x-icu"`, Vars: []interface{}{column}}
+		case "sqlserver":
+			return clause.Expr{SQL: "? COLLATE Chinese_PRC_CI_AS", Vars: []interface{}{column}}
+		default:
+			stmt.AddError(fmt.Errorf("%w: collation %s of %s, the dialector should implement CollateDialector", ErrUnsupportedDriver, collation, stmt.DB.Dialector.Name()))
+			return clause.Expr{SQL: "?", Vars: []interface{}{column}}
+		}
+	}
+
+	if !collationRegexp.MatchString(collation) {
+		stmt.AddError(fmt.Errorf("%w: invalid collation %s", ErrInvalidData, collation))
+		return clause.Expr{SQL: "?", Vars: []interface{}{column}}
+	}
+	return clause.Expr{SQL: "? COLLATE " + collation, Vars: []interface{}{column}}
+}
+
 // AddVar add var
 func (stmt *Statement) AddVar(writer clause.Writer, vars ...interface{}) {
 	for idx, v := range vars {
@@ -274,6 +304,24 @@ func (stmt *Statement) AddClause(v clause.Interface) {
 	}
 }
 
+// AddAndConditions add conditions that apply to all existing conditions of WHERE, which are wrapped in parentheses
+// first if they contain OR conditions, e.g. conditions of soft delete
+func (stmt *Statement) AddAndConditions(exprs ...clause.Expression) {
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			for _, expr := range where.Exprs {
+				if orCond, ok := expr.(clause.OrConditions); ok && len(orCond.Exprs) == 1 {
+					where.Exprs = []clause.Expression{clause.And(where.Exprs...)}
+					c.Expression = where
+					stmt.Clauses["WHERE"] = c
+					break
+				}
+			}
+		}
+	}
+	stmt.AddClause(clause.Where{Exprs: exprs})
+}
+
 // AddClauseIfNotExists add clause if not exists
 func (stmt *Statement) AddClauseIfNotExists(v clause.Interface) {
 	if c, ok := stmt.Clauses[v.Name()]; !ok || c.Expression == nil {
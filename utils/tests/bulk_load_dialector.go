@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"gorm.io/gorm"
+)
+
+// BulkLoadDialector dummy dialector implementing gorm.BulkLoadDialector, loaded rows are recorded by table,
+// it returns gorm.ErrNotImplemented to fall back to CreateInBatches if Unsupported
+type BulkLoadDialector struct {
+	DummyDialector
+	Unsupported bool
+	Columns     map[string][]string
+	Rows        map[string][][]interface{}
+}
+
+func (d *BulkLoadDialector) BulkLoad(tx *gorm.DB, table string, columns []string, rows gorm.BulkLoadRows) (rowsAffected int64, err error) {
+	if d.Unsupported {
+		return 0, gorm.ErrNotImplemented
+	}
+
+	if d.Columns == nil {
+		d.Columns = map[string][]string{}
+		d.Rows = map[string][][]interface{}{}
+	}
+	d.Columns[table] = columns
+
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return rowsAffected, err
+		}
+		d.Rows[table] = append(d.Rows[table], values)
+		rowsAffected++
+	}
+	return rowsAffected, rows.Err()
+}
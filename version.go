@@ -0,0 +1,187 @@
+package gorm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Version optimistic lock version, updates of a model with Version field are conditioned on its current version
+// and increase the version, ErrOptimisticLock is returned if the record has been updated by others
+//
+//	type Product struct {
+//		ID      uint
+//		Stock   int
+//		Version gorm.Version
+//	}
+//
+//	db.Model(&product).Update("stock", 10)
+//	// UPDATE products SET stock=10,version=version+1 WHERE id = 1 AND version = 3
+//
+// The version of an updating map, e.g. the version read by a client, is checked instead of the version of the model
+//
+//	db.Model(&Product{ID: 1}).Updates(map[string]interface{}{"stock": 10, "version": 2})
+//	// UPDATE products SET stock=10,version=version+1 WHERE id = 1 AND version = 2
+type Version sql.NullInt64
+
+// Scan implements the Scanner interface.
+func (v *Version) Scan(value interface{}) error {
+	return (*sql.NullInt64)(v).Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (v Version) Value() (driver.Value, error) {
+	if !v.Valid {
+		return nil, nil
+	}
+	return v.Int64, nil
+}
+
+func (v Version) MarshalJSON() ([]byte, error) {
+	if v.Valid {
+		return json.Marshal(v.Int64)
+	}
+	return json.Marshal(nil)
+}
+
+func (v *Version) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		v.Valid = false
+		return nil
+	}
+	err := json.Unmarshal(b, &v.Int64)
+	if err == nil {
+		v.Valid = true
+	}
+	return err
+}
+
+// lookUpVersionField the Version field of schema
+func lookUpVersionField(s *schema.Schema) *schema.Field {
+	versionType := reflect.TypeOf(Version{})
+	for _, field := range s.Fields {
+		if field.DBName != "" && field.IndirectFieldType == versionType {
+			return field
+		}
+	}
+	return nil
+}
+
+func (Version) CreateClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{VersionCreateClause{Field: f}}
+}
+
+type VersionCreateClause struct {
+	Field *schema.Field
+}
+
+func (v VersionCreateClause) Name() string {
+	return ""
+}
+
+func (v VersionCreateClause) Build(clause.Builder) {
+}
+
+func (v VersionCreateClause) MergeClause(*clause.Clause) {
+}
+
+// ModifyStatement initialize blank versions to 1
+func (v VersionCreateClause) ModifyStatement(stmt *Statement) {
+	if stmt.SQL.Len() > 0 {
+		return
+	}
+
+	initialize := func(rv reflect.Value) {
+		if _, isZero := v.Field.ValueOf(stmt.Context, rv); isZero {
+			stmt.AddError(v.Field.Set(stmt.Context, rv, Version{Int64: 1, Valid: true}))
+		}
+	}
+
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			if rv := reflect.Indirect(stmt.ReflectValue.Index(i)); rv.Kind() == reflect.Struct && rv.CanAddr() {
+				initialize(rv)
+			}
+		}
+	case reflect.Struct:
+		if stmt.ReflectValue.CanAddr() {
+			initialize(stmt.ReflectValue)
+		}
+	}
+}
+
+func (Version) UpdateClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{VersionUpdateClause{Field: f}}
+}
+
+// VersionUpdateClause adds the version condition to updates, the update callback increases the version
+// and returns ErrOptimisticLock if Checked and no record updated
+type VersionUpdateClause struct {
+	Field   *schema.Field
+	Checked bool
+	Current int64
+}
+
+func (v VersionUpdateClause) Name() string {
+	return ""
+}
+
+func (v VersionUpdateClause) Build(clause.Builder) {
+}
+
+func (v VersionUpdateClause) MergeClause(*clause.Clause) {
+}
+
+func (v VersionUpdateClause) ModifyStatement(stmt *Statement) {
+	if _, ok := stmt.Clauses["version_enabled"]; ok || stmt.SQL.Len() > 0 {
+		return
+	}
+
+	if version, ok := v.expectedVersion(stmt); ok {
+		stmt.AddAndConditions(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: v.Field.DBName}, Value: version.Int64})
+		v.Checked, v.Current = true, version.Int64
+	}
+
+	stmt.Clauses["version_enabled"] = clause.Clause{Expression: v}
+}
+
+// expectedVersion the version of the updating map if it has one, e.g. the version read by clients, otherwise the
+// version of the model
+func (v VersionUpdateClause) expectedVersion(stmt *Statement) (Version, bool) {
+	if values, ok := stmt.Dest.(map[string]interface{}); ok {
+		value, ok := values[v.Field.Name]
+		if !ok {
+			value, ok = values[v.Field.DBName]
+		}
+
+		if ok {
+			var version Version
+			switch value := value.(type) {
+			case Version:
+				version = value
+			case *Version:
+				if value != nil {
+					version = *value
+				}
+			default:
+				if err := version.Scan(value); err != nil {
+					return version, false
+				}
+			}
+			return version, version.Valid
+		}
+	}
+
+	if stmt.ReflectValue.Kind() == reflect.Struct {
+		if value, isZero := v.Field.ValueOf(stmt.Context, stmt.ReflectValue); !isZero {
+			version, ok := value.(Version)
+			return version, ok && version.Valid
+		}
+	}
+	return Version{}, false
+}
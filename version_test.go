@@ -0,0 +1,89 @@
+package gorm_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+type versionedProduct struct {
+	ID      uint
+	Name    string
+	Stock   int
+	Version gorm.Version
+}
+
+// execPool conn pool that reports rowsAffected for every exec
+type execPool struct {
+	gorm.ConnPool
+	rowsAffected int64
+}
+
+func (p *execPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return driver.RowsAffected(p.rowsAffected), nil
+}
+
+func TestVersion(t *testing.T) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	pool := &execPool{rowsAffected: 1}
+	db, _ := gorm.Open(tests.DummyDialector{}, &gorm.Config{ConnPool: pool, Logger: recorder, SkipDefaultTransaction: true})
+
+	product := versionedProduct{Name: "pen"}
+	db.Session(&gorm.Session{DryRun: true}).Create(&product)
+	if !product.Version.Valid || product.Version.Int64 != 1 {
+		t.Fatalf("version should be initialized to 1, got %+v", product.Version)
+	}
+
+	product.ID = 1
+	db.Model(&product).Update("stock", 10)
+	db.Model(&product).Select("name").Updates(versionedProduct{Name: "book", Version: gorm.Version{Int64: 9, Valid: true}})
+	db.Model(&product).Omit("name").Updates(map[string]interface{}{"name": "pencil", "stock": 20})
+	db.Save(&product)
+	tests.AssertEqual(t, recorder.SQLs[1:], []string{
+		"UPDATE `versioned_products` SET `stock`=10,`version`=`version` + 1 WHERE `versioned_products`.`version` = 1 AND `id` = 1",
+		"UPDATE `versioned_products` SET `name`=\"book\",`version`=`version` + 1 WHERE `versioned_products`.`version` = 2 AND `id` = 1",
+		"UPDATE `versioned_products` SET `stock`=20,`version`=`version` + 1 WHERE `versioned_products`.`version` = 3 AND `id` = 1",
+		"UPDATE `versioned_products` SET `name`=\"book\",`stock`=20,`version`=`version` + 1 WHERE `versioned_products`.`version` = 4 AND `id` = 1",
+	})
+
+	if product.Version.Int64 != 5 {
+		t.Errorf("version should be increased after updates, got %+v", product.Version)
+	}
+
+	// versions of maps are checked, e.g. versions read by clients
+	recorder.SQLs = nil
+	mapped := versionedProduct{ID: 2}
+	db.Model(&mapped).Updates(map[string]interface{}{"stock": 30, "version": 7})
+	db.Model(&versionedProduct{ID: 2}).Where("stock > ?", 0).Or("name = ?", "pen").Update("Version", gorm.Version{Int64: 8, Valid: true})
+	tests.AssertEqual(t, recorder.SQLs, []string{
+		"UPDATE `versioned_products` SET `stock`=30,`version`=`version` + 1 WHERE `versioned_products`.`version` = 7 AND `id` = 2",
+		"UPDATE `versioned_products` SET `version`=`version` + 1 WHERE (stock > 0 OR name = \"pen\") AND `versioned_products`.`version` = 8 AND `id` = 2",
+	})
+
+	if mapped.Version.Int64 != 8 {
+		t.Errorf("version of the model should be set after map updates, got %+v", mapped.Version)
+	}
+
+	pool.rowsAffected = 0
+	if err := db.Model(&product).Update("stock", 0).Error; !errors.Is(err, gorm.ErrOptimisticLock) {
+		t.Errorf("update of stale version should fail with ErrOptimisticLock, got %v", err)
+	}
+
+	if err := db.Save(&product).Error; !errors.Is(err, gorm.ErrOptimisticLock) {
+		t.Errorf("save of stale version should fail with ErrOptimisticLock, got %v", err)
+	}
+
+	if product.Version.Int64 != 5 {
+		t.Errorf("version should not be changed when update failed, got %+v", product.Version)
+	}
+
+	if err := db.Model(&versionedProduct{ID: 2}).Updates(map[string]interface{}{"stock": 40, "version": 8}).Error; !errors.Is(err, gorm.ErrOptimisticLock) {
+		t.Errorf("map update of stale version should fail with ErrOptimisticLock, got %v", err)
+	}
+}